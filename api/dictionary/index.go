@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hnimtadd/spaced/src/dictionary"
+	"github.com/hnimtadd/spaced/src/upstream"
+	"github.com/hnimtadd/spaced/src/utils"
+)
+
+// Handler looks up ?word= and returns the structured dictionary entry.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		utils.SError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET allowed")
+		return
+	}
+
+	word := strings.TrimSpace(r.URL.Query().Get("word"))
+	if word == "" {
+		utils.SError(w, http.StatusBadRequest, "invalid_request", "missing word query parameter")
+		return
+	}
+
+	entry, err := dictionary.Lookup(r.Context(), word)
+	if errors.Is(err, dictionary.ErrInvalidWord) {
+		utils.SError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if errors.Is(err, dictionary.ErrNotFound) {
+		utils.SError(w, http.StatusNotFound, "not_found", dictionary.ErrNotFound.Error())
+		return
+	}
+	if err != nil {
+		status, code := upstream.Classify(err)
+		utils.SError(w, status, code, err.Error())
+		return
+	}
+
+	utils.SMarshal(w, map[string]any{"payload": entry})
+}
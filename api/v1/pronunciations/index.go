@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/hnimtadd/spaced/src/pronunciation"
+	"github.com/hnimtadd/spaced/src/utils"
+)
+
+// Handler serves GET /api/v1/pronunciations?word=&ipa=&region=, answering
+// with audio/mpeg when the client accepts it and pronunciation.Response
+// otherwise.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Vary", "Accept")
+	if r.Method != http.MethodGet {
+		utils.SError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET allowed")
+		return
+	}
+
+	req, err := pronunciation.RequestFromQuery(r.URL.Query())
+	if err != nil {
+		pronunciation.WriteError(w, err)
+		return
+	}
+
+	p, err := pronunciation.Resolve(r.Context(), req)
+	if err != nil {
+		pronunciation.WriteError(w, err)
+		return
+	}
+
+	if pronunciation.AcceptsAudio(r) {
+		pronunciation.ServeAudio(w, r, p)
+		return
+	}
+	utils.SMarshal(w, map[string]any{"payload": pronunciation.Response{
+		Word:   p.Word,
+		IPA:    p.IPA,
+		Region: p.Region,
+		Source: p.Source,
+		Audio:  base64.StdEncoding.EncodeToString(p.Sound),
+	}})
+}
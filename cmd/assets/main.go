@@ -0,0 +1,116 @@
+// Command assets names the wasm module and the scripts of the ui after their
+// content, so browsers may keep them for good, and renders the index.html
+// referencing them. go generate runs it once the module is built:
+//
+//	go run ./cmd/assets ui
+//
+// assets/app.wasm is moved to assets/app.<hash>.wasm, main.js and
+// wasm_exec.js are copied to assets/<name>.<hash>.js, with the url of the
+// module in main.js replaced, and index.html is rendered from
+// index.html.tmpl. The hashed files of a previous run are removed.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// wasmURL is the url main.js loads the module from before it is hashed.
+const wasmURL = "/assets/app.wasm"
+
+// hashed matches the files a run writes, with their precompressed copies.
+var hashed = regexp.MustCompile(`^(app|main|wasm_exec)\.[0-9a-f]{10}\.(wasm|js)(\.gz|\.br)?$`)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: assets <ui dir>")
+		os.Exit(2)
+	}
+	urls, err := build(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "assets:", err)
+		os.Exit(1)
+	}
+	for _, name := range slices.Sorted(maps.Keys(urls)) {
+		fmt.Println(name, "->", urls[name])
+	}
+}
+
+// build hashes the assets of the ui in dir and renders its index.html, it
+// returns the url of every asset by name.
+func build(dir string) (map[string]string, error) {
+	assets := filepath.Join(dir, "assets")
+	module, err := os.ReadFile(filepath.Join(assets, "app.wasm"))
+	if err != nil {
+		return nil, fmt.Errorf("%w, build the module first", err)
+	}
+	script, err := os.ReadFile(filepath.Join(dir, "main.js"))
+	if err != nil {
+		return nil, err
+	}
+	exec, err := os.ReadFile(filepath.Join(dir, "wasm_exec.js"))
+	if err != nil {
+		return nil, err
+	}
+	if n := strings.Count(string(script), `"`+wasmURL+`"`); n != 1 {
+		return nil, fmt.Errorf("main.js: expected the module url %q once, found it %d times", wasmURL, n)
+	}
+	index, err := template.ParseFiles(filepath.Join(dir, "index.html.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(assets)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if hashed.MatchString(entry.Name()) {
+			if err := os.Remove(filepath.Join(assets, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	urls := map[string]string{}
+	write := func(name string, content []byte) error {
+		ext := path.Ext(name)
+		sum := sha256.Sum256(content)
+		hashedName := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:])[:10] + ext
+		urls[name] = "/assets/" + hashedName
+		return os.WriteFile(filepath.Join(assets, hashedName), content, 0o644)
+	}
+	if err := write("app.wasm", module); err != nil {
+		return nil, err
+	}
+	// the module is only served under its hashed name.
+	if err := os.Remove(filepath.Join(assets, "app.wasm")); err != nil {
+		return nil, err
+	}
+	script = []byte(strings.Replace(string(script), `"`+wasmURL+`"`, `"`+urls["app.wasm"]+`"`, 1))
+	if err := write("main.js", script); err != nil {
+		return nil, err
+	}
+	if err := write("wasm_exec.js", exec); err != nil {
+		return nil, err
+	}
+
+	out, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	if err := index.Execute(out, urls); err != nil {
+		return nil, err
+	}
+	return urls, out.Close()
+}
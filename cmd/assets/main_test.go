@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeUI(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuild(t *testing.T) {
+	dir := writeUI(t, map[string]string{
+		"assets/app.wasm":               "module",
+		"assets/cards.json":             "[]",
+		"assets/app.0000000000.wasm":    "stale",
+		"assets/app.0000000000.wasm.gz": "stale",
+		"main.js":                       `const WASM_URL = "/assets/app.wasm";`,
+		"wasm_exec.js":                  "go()",
+		"index.html.tmpl":               `<script src="{{index . "wasm_exec.js"}}"></script><script src="{{index . "main.js"}}"></script>`,
+	})
+	urls, err := build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, url := range urls {
+		if !hashed.MatchString(filepath.Base(url)) || !strings.HasPrefix(url, "/assets/") {
+			t.Errorf("%s: got url %s", name, url)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read(urls["app.wasm"]); got != "module" {
+		t.Errorf("module: got %q", got)
+	}
+	if got := read(urls["main.js"]); got != `const WASM_URL = "`+urls["app.wasm"]+`";` {
+		t.Errorf("main.js: got %q", got)
+	}
+	want := `<script src="` + urls["wasm_exec.js"] + `"></script><script src="` + urls["main.js"] + `"></script>`
+	if got := read("index.html"); got != want {
+		t.Errorf("index.html: got %q, want %q", got, want)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "assets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	// the unhashed module and the previous run are gone.
+	if len(names) != 4 || strings.Contains(strings.Join(names, " "), "0000000000") {
+		t.Errorf("got assets %v", names)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	files := map[string]string{
+		"assets/app.wasm": "module",
+		"main.js":         `const WASM_URL = "/assets/app.wasm";`,
+		"wasm_exec.js":    "go()",
+		"index.html.tmpl": "",
+	}
+	without := func(name string) map[string]string {
+		rest := map[string]string{}
+		for key, value := range files {
+			if key != name {
+				rest[key] = value
+			}
+		}
+		return rest
+	}
+	moved := without("main.js")
+	moved["main.js"] = `const WASM_URL = "/app.wasm";`
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"module not built", without("assets/app.wasm"), "build the module first"},
+		{"no template", without("index.html.tmpl"), "index.html.tmpl"},
+		{"module url moved", moved, "expected the module url"},
+	} {
+		_, err := build(writeUI(t, tt.files))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -1,79 +1,67 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 
-	coreHtml "github.com/hnimtadd/spaced/src/html"
-	"golang.org/x/net/html"
+	"github.com/hnimtadd/spaced/src/dictionary"
 )
 
+// parser prints the entry scraped from a saved dictionary page and the sound
+// picked for -ipa, -o also downloads the sound.
+//
+//	go run ./cmd/html/parser -ipa əˈɡriː ./src/dictionary/testdata/agree.html
 func main() {
-	file, _ := os.Open("./sample.html")
-	doc, _ := html.Parse(file)
-	region := "us"
-	ipa := "əˈɡriː"
+	region := flag.String("region", "us", "pronunciation region")
+	ipa := flag.String("ipa", "", "IPA of the wanted pronunciation")
+	output := flag.String("o", "", "download the sound to this file")
+	flag.Parse()
 
-	soundURLs := []string{}
-	var processWordNode coreHtml.WalkFunc = func(node *html.Node) error {
-		if node.Type != html.ElementNode ||
-			node.Data != "source" ||
-			!coreHtml.HasAttr(node.Attr, "type", "audio/mpeg") {
-			return nil
-		}
-
-		greatGrandParent := node.Parent.Parent.Parent
-
-		if greatGrandParent.Type != html.ElementNode ||
-			greatGrandParent.Data != "span" ||
-			!coreHtml.HasAttr(greatGrandParent.Attr, "class", region, "dpron-i") {
-			return nil
-		}
-
-		for child := range greatGrandParent.ChildNodes() {
-			if child.Type == html.ElementNode &&
-				child.Data == "span" &&
-				coreHtml.HasAttr(child.Attr, "class", "pron", "dpron") {
-				for grandChild := range child.ChildNodes() {
-					if grandChild.Type == html.ElementNode &&
-						grandChild.Data == "span" &&
-						coreHtml.HasAttr(grandChild.Attr, "class", "ipa", "dipa") {
-						if grandChild.FirstChild.Data == ipa {
-							soundURLs = append(soundURLs, coreHtml.GetAttr(node.Attr, "src"))
-						}
-
-						return coreHtml.ErrWalkSkip
-					}
-				}
-			}
-		}
-		return coreHtml.ErrWalkSkip
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: parser [-region us] [-ipa ipa] [-o sample.mp3] page.html")
+		os.Exit(2)
 	}
 
-	err := coreHtml.Walk(doc, func(node *html.Node) error {
-		if node.Type == html.ElementNode && node.Data == "div" && coreHtml.HasAttr(node.Attr, "class", "pos-header", "dpos-h") {
-			coreHtml.Walk(node, processWordNode)
-			return coreHtml.ErrWalkSkip
-		}
-		return nil
-	})
-	fmt.Println(err)
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		panic("failed to open page: " + err.Error())
+	}
+	defer file.Close()
 
-	fmt.Println(soundURLs)
+	entry, err := dictionary.Parse(file)
+	if err != nil {
+		panic("failed to parse page: " + err.Error())
+	}
+	entryBytes, _ := json.MarshalIndent(entry, "", "  ")
+	fmt.Println(string(entryBytes))
 
-	if len(soundURLs) == 0 {
-		panic("empty sound url")
+	soundURL, err := entry.SoundURL(*ipa, *region)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("sound:", soundURL)
+	if *output == "" {
+		return
 	}
-	url := "https://dictionary.cambridge.org/%s"
-	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf(url, soundURLs[0]), http.NoBody)
 
+	req, _ := http.NewRequest(http.MethodGet, dictionary.BaseURL+soundURL, http.NoBody)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic("failed to download sound: " + err.Error())
+	}
+	defer resp.Body.Close()
 
-	resp, _ := http.DefaultClient.Do(req)
-
-	file, _ = os.OpenFile("./sample.mp3", os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o777)
-
-	io.Copy(file, resp.Body)
+	out, err := os.OpenFile(*output, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o644)
+	if err != nil {
+		panic("failed to create output: " + err.Error())
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		panic("failed to write sound: " + err.Error())
+	}
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/deck"
+	"github.com/hnimtadd/spaced/src/dictionary"
+	"github.com/hnimtadd/spaced/src/utils"
+)
+
+// cardsHandler is the web counterpart of `spaced add`: GET returns one
+// candidate card per sense of each ?word=, POST appends the picked card to
+// the deck file. Adding requires the bearer token, without one it is
+// disabled.
+type cardsHandler struct {
+	mu       sync.Mutex
+	deckPath string
+	token    string
+	// limiter charges a token per word looked up, the request itself paid
+	// for the first one.
+	limiter *rateLimiter
+}
+
+// maxSuggestWords bounds the dictionary lookups a single request can cause,
+// the burst of the limiter lowers it.
+const maxSuggestWords = 20
+
+// maxCardBytes bounds the body of a POST, a card is a few hundred bytes.
+const maxCardBytes = 16 << 10
+
+// newCard is what a POST may set, the progress of a card starts over.
+type newCard struct {
+	Word       string `json:"word"`
+	IPA        string `json:"ipa"`
+	Definition string `json:"definition"`
+	Example    string `json:"example"`
+}
+
+type suggestion struct {
+	Word       string       `json:"word"`
+	Candidates []model.Card `json:"candidates,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+func (h *cardsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		h.suggest(w, r)
+	case http.MethodPost:
+		h.add(w, r)
+	default:
+		utils.SError(w, http.StatusMethodNotAllowed, "method_not_allowed", "only GET and POST allowed")
+	}
+}
+
+func (h *cardsHandler) suggest(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	if region == "" {
+		region = "us"
+	}
+
+	words := r.URL.Query()["word"]
+	if len(words) == 0 {
+		utils.SError(w, http.StatusBadRequest, "invalid_request", "missing word query parameter")
+		return
+	}
+	if limit := min(maxSuggestWords, h.limiter.capacity()); len(words) > limit {
+		utils.SError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("at most %d words per request", limit))
+		return
+	}
+	if ok, wait := h.limiter.allow(clientIP(r), len(words)-1, time.Now()); !ok {
+		tooManyRequests(w, wait)
+		return
+	}
+
+	suggestions := make([]suggestion, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		candidates, err := deck.Suggest(r.Context(), word, region)
+		if errors.Is(err, dictionary.ErrNotFound) || (err == nil && len(candidates) == 0) {
+			suggestions = append(suggestions, suggestion{Word: word, Error: dictionary.ErrNotFound.Error()})
+			continue
+		}
+		if err != nil {
+			suggestions = append(suggestions, suggestion{Word: word, Error: err.Error()})
+			continue
+		}
+		suggestions = append(suggestions, suggestion{Word: word, Candidates: candidates})
+	}
+	utils.SMarshal(w, map[string]any{"payload": suggestions})
+}
+
+func (h *cardsHandler) add(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" {
+		utils.SError(w, http.StatusForbidden, "forbidden", "adding cards is disabled, set cards.token")
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		utils.SError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid bearer token")
+		return
+	}
+
+	content := newCard{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCardBytes)).Decode(&content); err != nil {
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			utils.SError(w, http.StatusRequestEntityTooLarge, "invalid_request", fmt.Sprintf("card exceeds %d bytes", maxCardBytes))
+			return
+		}
+		utils.SError(w, http.StatusBadRequest, "invalid_request", "invalid card: "+err.Error())
+		return
+	}
+	card := model.Card{Word: content.Word, IPA: content.IPA, Definition: content.Definition, Example: content.Example}
+	if strings.TrimSpace(card.Word) == "" || strings.TrimSpace(card.Definition) == "" {
+		utils.SError(w, http.StatusBadRequest, "invalid_request", "card must have a word and a definition")
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	d, err := deck.Load(h.deckPath)
+	if err != nil {
+		utils.SError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	if err := d.Add(card); err != nil {
+		utils.SError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	}
+	if err := d.Save(h.deckPath); err != nil {
+		utils.SError(w, http.StatusInternalServerError, "internal", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	utils.SMarshal(w, map[string]any{"payload": d.Len()})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/core/model"
+)
+
+func TestCardsAdd(t *testing.T) {
+	deckPath := filepath.Join(t.TempDir(), "cards.json")
+	if err := os.WriteFile(deckPath, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := &cardsHandler{deckPath: deckPath, token: "secret"}
+	post := func(token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/cards", strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	card := `{"word":"tenet","definition":"a principle","due":"2030-01-01T00:00:00Z","reps":7,"state":2,"suspended":true,"audio":{"us":"audio:x"}}`
+	for _, tt := range []struct {
+		name  string
+		token string
+		body  string
+		want  int
+	}{
+		{"no token", "", card, http.StatusUnauthorized},
+		{"wrong token", "guess", card, http.StatusUnauthorized},
+		{"too large", "secret", `{"word":"` + strings.Repeat("a", maxCardBytes) + `"}`, http.StatusRequestEntityTooLarge},
+		{"no definition", "secret", `{"word":"tenet"}`, http.StatusBadRequest},
+		{"added", "secret", card, http.StatusCreated},
+		{"exists", "secret", card, http.StatusConflict},
+	} {
+		if w := post(tt.token, tt.body); w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+
+	data, err := os.ReadFile(deckPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cards := []model.Card{}
+	if err := json.Unmarshal(data, &cards); err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("got %d cards, want 1", len(cards))
+	}
+	want := model.Card{Word: "tenet", Definition: "a principle"}
+	if got := cards[0]; got.Word != want.Word || got.Definition != want.Definition || !got.Due.IsZero() ||
+		got.Reps != 0 || got.State != 0 || got.Suspended || len(got.Audio) != 0 {
+		t.Errorf("got %+v, want the progress of a new card", got)
+	}
+
+	h.token = ""
+	if w := post("secret", card); w.Code != http.StatusForbidden {
+		t.Errorf("without token: got %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestCardsSuggestCharge(t *testing.T) {
+	limiter := newRateLimiter(0.001, 4)
+	h := rateLimitMiddleware(limiter, &cardsHandler{limiter: limiter})
+	get := func(words ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/cards?word="+strings.Join(words, "&word="), nil)
+		// the lookups fail right away, only the charge matters.
+		ctx, cancel := context.WithCancel(r.Context())
+		cancel()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return w
+	}
+
+	if w := get("a", "b", "c", "d", "e"); w.Code != http.StatusBadRequest {
+		t.Errorf("more words than the burst: got %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	// one token for the request, and one per extra word: three are left
+	// and two words take two.
+	if w := get("a", "b"); w.Code != http.StatusOK {
+		t.Fatalf("two words: got %d %s", w.Code, w.Body)
+	}
+	w := get("a", "b")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("two words for the last token: got %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
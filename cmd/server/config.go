@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hnimtadd/spaced"
+	"github.com/hnimtadd/spaced/src/site"
+	"github.com/hnimtadd/spaced/src/upstream"
+)
+
+// config of the server, every setting comes from, by increasing priority,
+// the defaults, the optional config file, the environment and the flags.
+type config struct {
+	Addr string `json:"addr"`
+	// StaticDir serves the ui from disk instead of the embedded copy.
+	StaticDir string `json:"staticDir"`
+	DataDir   string `json:"dataDir"`
+	Provider  string `json:"provider"`
+	// Rewrites is the vercel.json whose rewrites apply to missing files.
+	Rewrites string `json:"rewrites"`
+	// Dev turns caching off, so edits to ui show up on reload.
+	Dev       bool            `json:"dev"`
+	TLS       tlsConfig       `json:"tls"`
+	Cache     cacheConfig     `json:"cache"`
+	CORS      corsConfig      `json:"cors"`
+	Log       logConfig       `json:"log"`
+	Timeouts  timeouts        `json:"timeouts"`
+	RateLimit rateLimitConfig `json:"rateLimit"`
+	Cards     cardsConfig     `json:"cards"`
+	Upstream  upstreamConfig  `json:"upstream"`
+}
+
+type tlsConfig struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+type cacheConfig struct {
+	// Disabled sends no-cache headers with every response.
+	Disabled bool `json:"disabled"`
+	// MaxAge is how long assets without a content hash in their name are
+	// used before being revalidated, 0 revalidates them on every load.
+	// Pages and the deck are always revalidated.
+	MaxAge duration `json:"maxAge"`
+}
+
+type corsConfig struct {
+	Origins []string `json:"origins"`
+}
+
+// rateLimitConfig is the token bucket of every client of the api, each
+// request takes a token and suggesting cards one per word.
+type rateLimitConfig struct {
+	// Rate is the tokens refilled per second.
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// cardsConfig guards the deck edited through /api/cards.
+type cardsConfig struct {
+	// Token is the bearer token POST /api/cards requires, adding cards is
+	// disabled without one.
+	Token secret `json:"token"`
+}
+
+// upstreamConfig bounds the load the api puts on the dictionary.
+type upstreamConfig struct {
+	// Concurrency is how many fetches run at once, Queue how many more wait
+	// for their turn before the api answers busy.
+	Concurrency int `json:"concurrency"`
+	Queue       int `json:"queue"`
+	// BreakerThreshold failures in a row stop the fetches for
+	// BreakerCooldown.
+	BreakerThreshold int      `json:"breakerThreshold"`
+	BreakerCooldown  duration `json:"breakerCooldown"`
+}
+
+type logConfig struct {
+	Format string `json:"format"`
+}
+
+// timeouts of the http.Server, Shutdown bounds how long in-flight requests
+// may take to finish once a stop signal is received.
+type timeouts struct {
+	ReadHeader duration `json:"readHeader"`
+	Read       duration `json:"read"`
+	Write      duration `json:"write"`
+	Idle       duration `json:"idle"`
+	Shutdown   duration `json:"shutdown"`
+}
+
+// duration reads "1h30m" styled strings from config files.
+type duration struct{ time.Duration }
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// secret is a setting -print-config hides.
+type secret string
+
+func (s secret) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return json.Marshal("")
+	}
+	return json.Marshal("********")
+}
+
+// defaultRewrites falls back to the embedded copy when it does not exist, so
+// the server also runs out of the repository.
+const defaultRewrites = "vercel.json"
+
+// providers are the pronunciation sources the server knows about.
+var providers = []string{"cambridge"}
+
+func defaultConfig() config {
+	return config{
+		Addr:     ":8080",
+		Provider: "cambridge",
+		Rewrites: defaultRewrites,
+		Cache: cacheConfig{
+			MaxAge: duration{0},
+		},
+		Log: logConfig{Format: "text"},
+		Timeouts: timeouts{
+			ReadHeader: duration{5 * time.Second},
+			Read:       duration{15 * time.Second},
+			// sounds are proxied from the dictionary, leave room for retries.
+			Write:    duration{60 * time.Second},
+			Idle:     duration{2 * time.Minute},
+			Shutdown: duration{30 * time.Second},
+		},
+		// the api proxies the dictionary, keep a single client from draining it.
+		RateLimit: rateLimitConfig{Rate: 5, Burst: 20},
+		Upstream: upstreamConfig{
+			Concurrency:      upstream.DefaultConcurrency,
+			Queue:            upstream.DefaultQueue,
+			BreakerThreshold: upstream.DefaultBreakerThreshold,
+			BreakerCooldown:  duration{upstream.DefaultBreakerCooldown},
+		},
+	}
+}
+
+// loadConfig builds the config from the command line args (without the
+// program name) and the environment, then validates it.
+func loadConfig(args []string, getenv func(string) string, output io.Writer) (*config, bool, error) {
+	set := flag.NewFlagSet("server", flag.ContinueOnError)
+	set.SetOutput(output)
+	set.Usage = func() {
+		fmt.Fprintln(set.Output(), "usage: server [flags] [static dir]")
+		fmt.Fprintln(set.Output())
+		fmt.Fprintln(set.Output(), "The ui needs the wasm module and the index.html go generate writes, run")
+		fmt.Fprintln(set.Output(), "go generate . before go build ./cmd/server or serving ui from disk.")
+		fmt.Fprintln(set.Output())
+		set.PrintDefaults()
+	}
+	var (
+		path        = set.String("config", getenv("SPACED_CONFIG"), "optional config `file`, .json or .toml (env SPACED_CONFIG)")
+		printConfig = set.Bool("print-config", false, "print the resolved config and exit")
+		addr        = set.String("addr", "", "listen `address` (env SPACED_ADDR, PORT)")
+		static      = set.String("static", "", "serve the ui from `dir` instead of the embedded one (env SPACED_STATIC_DIR)")
+		dataDir     = set.String("data-dir", "", "`dir` holding the editable deck, defaults to <static>/assets, or ./data with the embedded ui (env SPACED_DATA_DIR)")
+		rewrites    = set.String("rewrites", "", "vercel.json `file` whose rewrites apply to missing files, empty for none (env SPACED_REWRITES)")
+		dev         = set.Bool("dev", false, "development mode, disables caching (env SPACED_DEV)")
+		provider    = set.String("provider", "", "pronunciation provider: "+strings.Join(providers, ", ")+" (env SPACED_PROVIDER)")
+		tlsCert     = set.String("tls-cert", "", "TLS certificate `file` (env SPACED_TLS_CERT)")
+		tlsKey      = set.String("tls-key", "", "TLS key `file` (env SPACED_TLS_KEY)")
+		noCache     = set.Bool("no-cache", false, "send no-cache headers with every response (env SPACED_CACHE_DISABLED)")
+		maxAge      = set.Duration("cache-max-age", 0, "max age of cacheable responses (env SPACED_CACHE_MAX_AGE)")
+		origins     = set.String("cors-origins", "", "comma separated `origins` allowed to call the api (env SPACED_CORS_ORIGINS)")
+		logFormat   = set.String("log-format", "", "log format: text or json (env SPACED_LOG_FORMAT)")
+		shutdown    = set.Duration("shutdown-timeout", 0, "how long in-flight requests may take to finish on stop (env SPACED_SHUTDOWN_TIMEOUT)")
+		rate        = set.Float64("rate-limit", 0, "api tokens refilled per second and client (env SPACED_RATE_LIMIT)")
+		burst       = set.Int("rate-burst", 0, "api tokens a client can spend at once (env SPACED_RATE_BURST)")
+		concurrency = set.Int("upstream-concurrency", 0, "dictionary fetches running at once (env SPACED_UPSTREAM_CONCURRENCY)")
+		queue       = set.Int("upstream-queue", 0, "dictionary fetches waiting for a slot before the api answers busy (env SPACED_UPSTREAM_QUEUE)")
+		threshold   = set.Int("upstream-breaker-threshold", 0, "dictionary failures in a row stopping the fetches (env SPACED_UPSTREAM_BREAKER_THRESHOLD)")
+		cooldown    = set.Duration("upstream-breaker-cooldown", 0, "how long the dictionary fetches stay stopped once the breaker opens (env SPACED_UPSTREAM_BREAKER_COOLDOWN)")
+	)
+	if err := set.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if set.NArg() > 1 {
+		return nil, false, fmt.Errorf("expected at most one static dir argument, got %q", set.Args())
+	}
+
+	cfg := defaultConfig()
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return nil, false, err
+	}
+
+	// go run ./cmd/server folder
+	if set.NArg() == 1 {
+		cfg.StaticDir = set.Arg(0)
+	}
+	set.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "static":
+			cfg.StaticDir = *static
+		case "data-dir":
+			cfg.DataDir = *dataDir
+		case "rewrites":
+			cfg.Rewrites = *rewrites
+		case "dev":
+			cfg.Dev = *dev
+		case "provider":
+			cfg.Provider = *provider
+		case "tls-cert":
+			cfg.TLS.Cert = *tlsCert
+		case "tls-key":
+			cfg.TLS.Key = *tlsKey
+		case "no-cache":
+			cfg.Cache.Disabled = *noCache
+		case "cache-max-age":
+			cfg.Cache.MaxAge = duration{*maxAge}
+		case "cors-origins":
+			cfg.CORS.Origins = splitList(*origins)
+		case "log-format":
+			cfg.Log.Format = *logFormat
+		case "shutdown-timeout":
+			cfg.Timeouts.Shutdown = duration{*shutdown}
+		case "rate-limit":
+			cfg.RateLimit.Rate = *rate
+		case "rate-burst":
+			cfg.RateLimit.Burst = *burst
+		case "upstream-concurrency":
+			cfg.Upstream.Concurrency = *concurrency
+		case "upstream-queue":
+			cfg.Upstream.Queue = *queue
+		case "upstream-breaker-threshold":
+			cfg.Upstream.BreakerThreshold = *threshold
+		case "upstream-breaker-cooldown":
+			cfg.Upstream.BreakerCooldown = duration{*cooldown}
+		}
+	})
+	if cfg.DataDir == "" && cfg.StaticDir != "" {
+		cfg.DataDir = filepath.Join(cfg.StaticDir, "assets")
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = "data"
+	}
+	if cfg.Dev {
+		cfg.Cache.Disabled = true
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, false, err
+	}
+	return &cfg, *printConfig, nil
+}
+
+// readFile overrides cfg with the settings of a json or toml file, the
+// settings missing from the file are kept.
+func (cfg *config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+	case ".toml":
+		table, err := parseTOML(string(data))
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+		// both formats share the json field names.
+		if data, err = json.Marshal(table); err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config: %s: unsupported extension %q, use .json or .toml", path, ext)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *config) applyEnv(getenv func(string) string) error {
+	// PORT is what hosting platforms set, SPACED_ADDR wins when both are.
+	if port := getenv("PORT"); port != "" {
+		cfg.Addr = ":" + port
+	}
+	fields := map[string]*string{
+		"SPACED_ADDR":       &cfg.Addr,
+		"SPACED_STATIC_DIR": &cfg.StaticDir,
+		"SPACED_DATA_DIR":   &cfg.DataDir,
+		"SPACED_PROVIDER":   &cfg.Provider,
+		"SPACED_REWRITES":   &cfg.Rewrites,
+		"SPACED_TLS_CERT":   &cfg.TLS.Cert,
+		"SPACED_TLS_KEY":    &cfg.TLS.Key,
+		"SPACED_LOG_FORMAT": &cfg.Log.Format,
+	}
+	for key, field := range fields {
+		if value := getenv(key); value != "" {
+			*field = value
+		}
+	}
+
+	if value := getenv("SPACED_DEV"); value != "" {
+		dev, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_DEV: %w", err)
+		}
+		cfg.Dev = dev
+	}
+	if value := getenv("SPACED_CACHE_DISABLED"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_CACHE_DISABLED: %w", err)
+		}
+		cfg.Cache.Disabled = disabled
+	}
+	if value := getenv("SPACED_CACHE_MAX_AGE"); value != "" {
+		maxAge, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_CACHE_MAX_AGE: %w", err)
+		}
+		cfg.Cache.MaxAge = duration{maxAge}
+	}
+	if value := getenv("SPACED_SHUTDOWN_TIMEOUT"); value != "" {
+		shutdown, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_SHUTDOWN_TIMEOUT: %w", err)
+		}
+		cfg.Timeouts.Shutdown = duration{shutdown}
+	}
+	if value := getenv("SPACED_CORS_ORIGINS"); value != "" {
+		cfg.CORS.Origins = splitList(value)
+	}
+	if value := getenv("SPACED_RATE_LIMIT"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_RATE_LIMIT: %w", err)
+		}
+		cfg.RateLimit.Rate = rate
+	}
+	if value := getenv("SPACED_RATE_BURST"); value != "" {
+		burst, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_RATE_BURST: %w", err)
+		}
+		cfg.RateLimit.Burst = burst
+	}
+	ints := map[string]*int{
+		"SPACED_UPSTREAM_CONCURRENCY":       &cfg.Upstream.Concurrency,
+		"SPACED_UPSTREAM_QUEUE":             &cfg.Upstream.Queue,
+		"SPACED_UPSTREAM_BREAKER_THRESHOLD": &cfg.Upstream.BreakerThreshold,
+	}
+	for key, field := range ints {
+		if value := getenv(key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("config: %s: %w", key, err)
+			}
+			*field = n
+		}
+	}
+	if value := getenv("SPACED_UPSTREAM_BREAKER_COOLDOWN"); value != "" {
+		cooldown, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("config: SPACED_UPSTREAM_BREAKER_COOLDOWN: %w", err)
+		}
+		cfg.Upstream.BreakerCooldown = duration{cooldown}
+	}
+	// no flag, it would show up in the process list.
+	if value := getenv("SPACED_CARDS_TOKEN"); value != "" {
+		cfg.Cards.Token = secret(value)
+	}
+	return nil
+}
+
+// validate reports every invalid setting at once.
+func (cfg *config) validate() error {
+	errs := []error{}
+	invalid := func(setting, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("config: %s: "+format, append([]any{setting}, args...)...))
+	}
+
+	if _, port, err := net.SplitHostPort(cfg.Addr); err != nil {
+		invalid("addr", "%q is not a host:port address", cfg.Addr)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		invalid("addr", "invalid port %q", port)
+	}
+
+	if cfg.StaticDir != "" {
+		if info, err := os.Stat(cfg.StaticDir); err != nil {
+			invalid("staticDir", "%v", err)
+		} else if !info.IsDir() {
+			invalid("staticDir", "%s is not a directory", cfg.StaticDir)
+		}
+	}
+	// a missing data dir is created on startup.
+	if info, err := os.Stat(cfg.DataDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		invalid("dataDir", "%v", err)
+	} else if err == nil && !info.IsDir() {
+		invalid("dataDir", "%s is not a directory", cfg.DataDir)
+	}
+
+	if _, err := cfg.rewrites(); err != nil {
+		invalid("rewrites", "%v", err)
+	}
+
+	known := false
+	for _, provider := range providers {
+		known = known || cfg.Provider == provider
+	}
+	if !known {
+		invalid("provider", "unknown provider %q, expected one of %s", cfg.Provider, strings.Join(providers, ", "))
+	}
+
+	switch {
+	case (cfg.TLS.Cert == "") != (cfg.TLS.Key == ""):
+		invalid("tls", "cert and key must be set together")
+	case cfg.TLS.Cert != "":
+		if _, err := os.Stat(cfg.TLS.Cert); err != nil {
+			invalid("tls.cert", "%v", err)
+		}
+		if _, err := os.Stat(cfg.TLS.Key); err != nil {
+			invalid("tls.key", "%v", err)
+		}
+	}
+
+	if cfg.Cache.MaxAge.Duration < 0 {
+		invalid("cache.maxAge", "must not be negative, got %s", cfg.Cache.MaxAge)
+	}
+
+	for _, origin := range cfg.CORS.Origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			invalid("cors.origins", "%q is not an origin like https://example.com", origin)
+		}
+	}
+
+	for _, timeout := range []struct {
+		setting string
+		value   duration
+	}{
+		{"timeouts.readHeader", cfg.Timeouts.ReadHeader},
+		{"timeouts.read", cfg.Timeouts.Read},
+		{"timeouts.write", cfg.Timeouts.Write},
+		{"timeouts.idle", cfg.Timeouts.Idle},
+		{"timeouts.shutdown", cfg.Timeouts.Shutdown},
+	} {
+		if timeout.value.Duration <= 0 {
+			invalid(timeout.setting, "must be positive, got %s", timeout.value)
+		}
+	}
+
+	if cfg.RateLimit.Rate <= 0 {
+		invalid("rateLimit.rate", "must be positive, got %g", cfg.RateLimit.Rate)
+	}
+	if cfg.RateLimit.Burst < 1 {
+		invalid("rateLimit.burst", "must be at least 1, got %d", cfg.RateLimit.Burst)
+	}
+
+	if cfg.Upstream.Concurrency < 1 {
+		invalid("upstream.concurrency", "must be at least 1, got %d", cfg.Upstream.Concurrency)
+	}
+	if cfg.Upstream.Queue < 0 {
+		invalid("upstream.queue", "must not be negative, got %d", cfg.Upstream.Queue)
+	}
+	if cfg.Upstream.BreakerThreshold < 1 {
+		invalid("upstream.breakerThreshold", "must be at least 1, got %d", cfg.Upstream.BreakerThreshold)
+	}
+	if cfg.Upstream.BreakerCooldown.Duration <= 0 {
+		invalid("upstream.breakerCooldown", "must be positive, got %s", cfg.Upstream.BreakerCooldown)
+	}
+
+	if cfg.Log.Format != "text" && cfg.Log.Format != "json" {
+		invalid("log.format", "expected text or json, got %q", cfg.Log.Format)
+	}
+	return errors.Join(errs...)
+}
+
+// rewrites reads the configured rewrites, the embedded vercel.json stands in
+// for a missing default file.
+func (cfg *config) rewrites() ([]site.Rewrite, error) {
+	if cfg.Rewrites == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(cfg.Rewrites)
+	if errors.Is(err, os.ErrNotExist) && cfg.Rewrites == defaultRewrites {
+		data, err = spaced.VercelJSON, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rewrites, err := site.ReadRewrites(data)
+	if err != nil {
+		return nil, err
+	}
+	// compiles the sources.
+	if _, err := site.New(nil, rewrites); err != nil {
+		return nil, err
+	}
+	return rewrites, nil
+}
+
+func (cfg *config) tls() bool { return cfg.TLS.Cert != "" }
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
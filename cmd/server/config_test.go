@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigUpstream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spaced.toml")
+	src := "[upstream]\nconcurrency = 2\nqueue = 4\nbreakerThreshold = 3\nbreakerCooldown = \"1m\""
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"SPACED_CONFIG":                     path,
+		"SPACED_DATA_DIR":                   t.TempDir(),
+		"SPACED_UPSTREAM_QUEUE":             "0",
+		"SPACED_UPSTREAM_BREAKER_THRESHOLD": "10",
+	}
+	cfg, _, err := loadConfig([]string{"-upstream-breaker-threshold", "20"}, func(key string) string { return env[key] }, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := upstreamConfig{Concurrency: 2, Queue: 0, BreakerThreshold: 20, BreakerCooldown: duration{time.Minute}}
+	if cfg.Upstream != want {
+		t.Errorf("got %+v, want %+v", cfg.Upstream, want)
+	}
+
+	env["SPACED_UPSTREAM_QUEUE"] = "-1"
+	if _, _, err := loadConfig(nil, func(key string) string { return env[key] }, io.Discard); err == nil {
+		t.Error("expected a negative queue to be rejected")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/hnimtadd/spaced/src/upstream"
+	"github.com/hnimtadd/spaced/src/utils"
+)
+
+var errShuttingDown = errors.New("server is shutting down")
+
+// health answers the liveness and readiness probes of the server.
+type health struct {
+	provider string
+	dataDir  string
+	deckPath string
+	// draining is set once shutdown starts, so load balancers stop routing
+	// new requests while the in-flight ones finish.
+	draining atomic.Bool
+}
+
+type check struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// live reports the process is up and serving.
+func (h *health) live(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	utils.SMarshal(w, map[string]any{"payload": map[string]any{"status": "ok"}})
+}
+
+// ready reports whether requests can be served: the server is not shutting
+// down, the pronunciation provider is reachable and the deck storage works.
+func (h *health) ready(w http.ResponseWriter, _ *http.Request) {
+	checks := []check{
+		h.check("server", h.checkServer),
+		h.check("provider:"+h.provider, h.checkProvider),
+		h.check("storage", h.checkStorage),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	status := "ok"
+	for _, c := range checks {
+		if !c.OK {
+			status = "unavailable"
+			w.WriteHeader(http.StatusServiceUnavailable)
+			break
+		}
+	}
+	utils.SMarshal(w, map[string]any{"payload": map[string]any{"status": status, "checks": checks}})
+}
+
+func (h *health) check(name string, fn func() error) check {
+	if err := fn(); err != nil {
+		return check{Name: name, Error: err.Error()}
+	}
+	return check{Name: name, OK: true}
+}
+
+func (h *health) checkServer() error {
+	if h.draining.Load() {
+		return errShuttingDown
+	}
+	return nil
+}
+
+// checkProvider relies on the circuit breaker of the upstream client rather
+// than probing the dictionary on every call.
+func (h *health) checkProvider() error {
+	if upstream.Default.CircuitOpen() {
+		return upstream.ErrCircuitOpen
+	}
+	return nil
+}
+
+// checkStorage makes sure the deck can be opened and the data dir written,
+// as /api/cards needs both. The deck is not parsed, probes come often and it
+// grows with every card. A missing deck is an empty one.
+func (h *health) checkStorage() error {
+	file, err := os.Open(h.deckPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		_ = file.Close()
+	}
+
+	probe, err := os.CreateTemp(h.dataDir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	_ = probe.Close()
+	return os.Remove(probe.Name())
+}
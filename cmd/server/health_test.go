@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckStorage(t *testing.T) {
+	dir := t.TempDir()
+	deckPath := filepath.Join(dir, "cards.json")
+	h := &health{dataDir: dir, deckPath: deckPath}
+	if err := h.checkStorage(); err != nil {
+		t.Errorf("missing deck: %v", err)
+	}
+	// not parsed, a corrupt deck is reported by /api/cards instead.
+	if err := os.WriteFile(deckPath, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.checkStorage(); err != nil {
+		t.Errorf("existing deck: %v", err)
+	}
+
+	h.deckPath = filepath.Join(deckPath, "cards.json")
+	if err := h.checkStorage(); err == nil {
+		t.Error("deck under a file: got no error")
+	}
+	h = &health{dataDir: filepath.Join(dir, "missing"), deckPath: deckPath}
+	if err := h.checkStorage(); err == nil {
+		t.Error("missing data dir: got no error")
+	}
+}
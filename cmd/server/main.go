@@ -1,72 +1,235 @@
 package main
 
 import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io/fs"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 
+	"github.com/hnimtadd/spaced"
+	dictionary "github.com/hnimtadd/spaced/api/dictionary"
 	handler "github.com/hnimtadd/spaced/api/sound"
+	pronunciations "github.com/hnimtadd/spaced/api/v1/pronunciations"
+	"github.com/hnimtadd/spaced/src/upstream"
 )
 
-type httpFs struct {
-	fs.FS
-	baseDir string
-}
+//go:embed openapi.json
+var openAPI []byte
 
-func (fs httpFs) Open(name string) (fs.File, error) {
-	name = filepath.Join(fs.baseDir, name)
-	retried := false
-retry:
-	_, err := os.Stat(name)
+func main() {
+	cfg, printConfig, err := loadConfig(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		if retried {
-			return nil, err
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if printConfig {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(cfg)
+		return
+	}
+	setupLogger(cfg.Log)
+	upstream.Default.SetConcurrency(cfg.Upstream.Concurrency, cfg.Upstream.Queue)
+	upstream.Default.SetBreaker(cfg.Upstream.BreakerThreshold, cfg.Upstream.BreakerCooldown.Duration)
+
+	svc := http.NewServeMux()
+	limiter := newRateLimiter(cfg.RateLimit.Rate, cfg.RateLimit.Burst)
+	api := func(next http.Handler) http.Handler {
+		return loggingMiddleware(corsMiddleware(cfg.CORS.Origins, rateLimitMiddleware(limiter, next)))
+	}
+	// the sound of a word does not change, clients keep it and revalidate
+	// it with its ETag.
+	sounds := func(next http.Handler) http.Handler {
+		if cfg.Cache.Disabled {
+			return api(disableCacheMiddelware(next))
 		}
-		name = name + ".html"
-		retried = true
-		goto retry
+		return api(cacheMiddleware(soundCache, next))
 	}
+	svc.Handle("/api/sound/index", sounds(http.HandlerFunc(handler.Handler)))
+	svc.Handle("/api/v1/pronunciations", sounds(http.HandlerFunc(pronunciations.Handler)))
+	svc.HandleFunc("/api/v1/openapi.json", loggingMiddlewareFunc(corsMiddlewareFunc(cfg.CORS.Origins, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPI)
+	})))
+	svc.Handle("/api/dictionary", api(disableCacheMiddelware(http.HandlerFunc(dictionary.Handler))))
 
-	f, err := os.Open(name)
+	ui, source, err := uiFS(cfg.StaticDir)
 	if err != nil {
-		return nil, err
+		log.Fatal(err)
 	}
-	return f, nil
-}
+	deckPath := filepath.Join(cfg.DataDir, "cards.json")
+	if err := seedDeck(ui, cfg.DataDir, deckPath); err != nil {
+		log.Fatal(err)
+	}
+	probes := &health{provider: cfg.Provider, dataDir: cfg.DataDir, deckPath: deckPath}
+	svc.HandleFunc("/healthz", disableCacheMiddlewareFunc(probes.live))
+	svc.HandleFunc("/readyz", disableCacheMiddlewareFunc(probes.ready))
+	svc.HandleFunc("/metrics", disableCacheMiddlewareFunc(metricsHandler))
+	svc.Handle("/api/cards", api(disableCacheMiddelware(&cardsHandler{deckPath: deckPath, token: string(cfg.Cards.Token), limiter: limiter})))
+	// the deck edited through /api/cards replaces the static one.
+	data, err := newStaticHandler(os.DirFS(cfg.DataDir), nil, cfg.Cache)
+	if err != nil {
+		log.Fatal(err)
+	}
+	svc.Handle("/assets/cards.json", loggingMiddleware(staticMiddleware(cfg.Cache, http.StripPrefix("/assets/", data))))
 
-func main() {
-	var staticFilesDir string
-	if len(os.Args) == 1 {
-		staticFilesDir = "./" // Current directory
-	} else {
-		// go run ./cmd/server folder
-		staticFilesDir = os.Args[1]
+	rewrites, err := cfg.rewrites()
+	if err != nil {
+		log.Fatal(err)
+	}
+	static, err := newStaticHandler(ui, rewrites, cfg.Cache)
+	if err != nil {
+		log.Fatal(err)
 	}
+	svc.Handle("/", loggingMiddleware(staticMiddleware(cfg.Cache, static)))
 
-	if _, err := os.Stat(staticFilesDir); os.IsNotExist(err) {
-		log.Fatalf("Error: Static files directory '%s' does not exist. Please create it and place your files there.", staticFilesDir)
+	// requests keep running during the shutdown grace period, base is only
+	// canceled once it is over to abort the upstream fetches still pending.
+	base, abort := context.WithCancel(context.Background())
+	defer abort()
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           svc,
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader.Duration,
+		ReadTimeout:       cfg.Timeouts.Read.Duration,
+		WriteTimeout:      cfg.Timeouts.Write.Duration,
+		IdleTimeout:       cfg.Timeouts.Idle.Duration,
+		BaseContext:       func(net.Listener) context.Context { return base },
 	}
-	svc := http.NewServeMux()
-	fs := http.FileServerFS(httpFs{
-		baseDir: staticFilesDir,
-	})
 
-	svc.HandleFunc("/api/sound/index", loggingMiddlewareFunc(disableCacheMiddlewareFunc(http.HandlerFunc(handler.Handler))))
-	svc.Handle("/", loggingMiddleware(disableCacheMiddelware(fs)))
+	scheme := "http"
+	if cfg.tls() {
+		scheme = "https"
+	}
+	fmt.Printf("Serving static files from %s on %s://%s\n", source, scheme, displayAddr(cfg.Addr))
+	fmt.Println("Press Ctrl+C to stop the server.")
+
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		if cfg.tls() {
+			served <- server.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+			return
+		}
+		served <- server.ListenAndServe()
+	}()
 
-	port := "8080"
-	if p := os.Getenv("PORT"); p != "" {
-		port = p
+	select {
+	case err := <-served:
+		log.Fatal(err)
+	case <-stop.Done():
 	}
+	// a second signal kills the process right away.
+	cancel()
 
-	addr := fmt.Sprintf(":%s", port)
+	log.Printf("shutting down, waiting up to %s for in-flight requests", cfg.Timeouts.Shutdown)
+	probes.draining.Store(true)
+	ctx, done := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown.Duration)
+	defer done()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v, closing remaining connections", err)
+		abort()
+		_ = server.Close()
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("server: %v", err)
+	}
+	log.Println("server stopped")
+}
 
-	fmt.Printf("Serving static files from '%s' on http://localhost%s\n", filepath.Join(staticFilesDir), addr)
-	fmt.Println("Press Ctrl+C to stop the server.")
+// uiFS returns the on-disk ui when dir is set, else the embedded one. Both
+// must hold the files go generate writes.
+func uiFS(dir string) (fs.FS, string, error) {
+	ui, source := os.DirFS(dir), fmt.Sprintf("'%s'", filepath.Clean(dir))
+	if dir == "" {
+		sub, err := fs.Sub(spaced.UI, "ui")
+		if err != nil {
+			return nil, "", err
+		}
+		ui, source = sub, "the embedded ui"
+	}
+	if err := checkUI(ui); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", source, err)
+	}
+	return ui, source, nil
+}
+
+// checkUI fails when the page or the wasm module of ui were not generated,
+// the site would load without working otherwise.
+func checkUI(ui fs.FS) error {
+	missing := []string{}
+	if _, err := fs.Stat(ui, "index.html"); err != nil {
+		missing = append(missing, "index.html")
+	}
+	if modules, err := fs.Glob(ui, "assets/app.*.wasm"); err != nil || len(modules) == 0 {
+		missing = append(missing, "assets/app.<hash>.wasm")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s, run go generate . first", strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// seedDeck creates the data dir and copies the deck shipped with the ui into
+// it, so cards added through /api/cards extend it instead of replacing it.
+func seedDeck(ui fs.FS, dataDir, deckPath string) error {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(deckPath); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	cards, err := fs.ReadFile(ui, "assets/cards.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(deckPath, cards, 0o644)
+}
+
+// staticMiddleware keeps the no-cache headers of older browsers in dev mode,
+// the static handlers set Cache-Control themselves.
+func staticMiddleware(cache cacheConfig, next http.Handler) http.Handler {
+	if cache.Disabled {
+		return disableCacheMiddelware(next)
+	}
+	return next
+}
+
+// setupLogger makes slog, and the log package through it, write in the
+// configured format.
+func setupLogger(cfg logConfig) {
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, nil)
+	}
+	slog.SetDefault(slog.New(handler))
+}
 
-	// Start the HTTP server. log.Fatal will print an error and exit if the server fails to start.
-	log.Fatal(http.ListenAndServe(addr, svc))
+// displayAddr turns ":8080" into "localhost:8080" for the startup banner.
+func displayAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("localhost", port)
 }
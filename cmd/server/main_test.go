@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUIFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// the tracked sources, before go generate ran.
+	for _, name := range []string{"index.html.tmpl", "main.js", "assets/cards.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _, err := uiFS(dir)
+	if err == nil || !strings.Contains(err.Error(), "missing index.html and assets/app.<hash>.wasm, run go generate . first") {
+		t.Errorf("got %v", err)
+	}
+
+	for _, name := range []string{"index.html", "assets/app.3f2a9c1d07.wasm"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := uiFS(dir); err != nil {
+		t.Errorf("generated ui: %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/hnimtadd/spaced/src/metrics"
+)
+
+var (
+	requestsTotal = metrics.Default.Counter("spaced_http_requests_total",
+		"Requests served by route, method and status.", "route", "method", "status")
+	requestDuration = metrics.Default.Histogram("spaced_http_request_duration_seconds",
+		"Time to serve a request by route and method.", metrics.DefaultBuckets, "route", "method")
+	requestsInFlight = metrics.Default.Gauge("spaced_http_requests_in_flight",
+		"Requests being served.")
+	// the hit ratio of the browser caches is hit / (hit + miss).
+	staticResponses = metrics.Default.Counter("spaced_static_responses_total",
+		"Static files answered, hit when the client copy was still fresh (304), miss when the file was sent.", "result")
+	etagCache = metrics.Default.Counter("spaced_static_etag_cache_total",
+		"Lookups of the content hash of static files, miss when the file had to be hashed.", "result")
+)
+
+// metricsHandler serves every metric in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.Default.WriteText(w)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/logged/implicit", loggingMiddlewareFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	mux.HandleFunc("/logged/explicit", loggingMiddlewareFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+	mux.HandleFunc("/logged/empty", loggingMiddlewareFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	samples := []string{
+		`spaced_http_requests_total{route="/logged/implicit",method="GET",status="200"}`,
+		`spaced_http_requests_total{route="/logged/explicit",method="GET",status="418"}`,
+		`spaced_http_request_duration_seconds_count{route="/logged/empty",method="GET"}`,
+	}
+	before := scrape(t)
+	for _, tt := range []struct {
+		path     string
+		status   int
+		bytes    int
+		duration float64
+	}{
+		{"/logged/implicit", http.StatusOK, 5, 2},
+		{"/logged/explicit", http.StatusTeapot, 15, 0},
+		{"/logged/empty", http.StatusOK, 0, 0},
+	} {
+		logs.Reset()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || w.Header().Get("X-Request-Id") == "" {
+			t.Errorf("%s: got %d, request id %q", tt.path, w.Code, w.Header().Get("X-Request-Id"))
+		}
+
+		line := struct {
+			Msg        string  `json:"msg"`
+			RequestID  string  `json:"request_id"`
+			Route      string  `json:"route"`
+			Status     int     `json:"status"`
+			Bytes      int     `json:"bytes"`
+			DurationMS float64 `json:"duration_ms"`
+		}{}
+		if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+			t.Fatalf("%s: %v in %q", tt.path, err, logs.String())
+		}
+		if line.Msg != "request" || line.RequestID != w.Header().Get("X-Request-Id") || line.Route != tt.path ||
+			line.Status != tt.status || line.Bytes != tt.bytes || line.DurationMS < tt.duration {
+			t.Errorf("%s: got %+v", tt.path, line)
+		}
+	}
+
+	for _, sample := range samples {
+		if got := scrape(t)[sample]; got != before[sample]+1 {
+			t.Errorf("%s: got %v, want %v", sample, got, before[sample]+1)
+		}
+	}
+	if got := scrape(t)["spaced_http_requests_in_flight"]; got != 0 {
+		t.Errorf("got %v requests in flight", got)
+	}
+}
+
+// scrape reads the samples served on /metrics.
+func scrape(t *testing.T) map[string]float64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metricsHandler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type %q", got)
+	}
+	samples := map[string]float64{}
+	for line := range strings.Lines(w.Body.String()) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("sample %q: %v", line, err)
+		}
+		samples[name] = v
+	}
+	return samples
+}
@@ -1,20 +1,24 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
-// loggingMiddleware wraps an http.Handler to add logging functionality.
+// disableCacheMiddelware sends no-cache headers, for the api and dev mode.
 func disableCacheMiddelware(next http.Handler) http.Handler {
 	return disableCacheMiddlewareFunc(next.ServeHTTP)
 }
 
 func disableCacheMiddlewareFunc(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Serve the request using the next handler in the chain
-		log.Println("disabling cache")
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		w.Header().Set("Pragma", "no-cache") // For older browsers
 		w.Header().Set("Expires", "0")       // For older browsers
@@ -22,10 +26,54 @@ func disableCacheMiddlewareFunc(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// loggingResponseWriter is a custom http.ResponseWriter to capture the status code.
+// soundCache is the policy of the pronunciation endpoints.
+const soundCache = "public, max-age=86400"
+
+// cacheMiddleware sends policy with the responses of next, error responses
+// are not stored.
+func cacheMiddleware(policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&cacheResponseWriter{ResponseWriter: w, policy: policy}, r)
+	})
+}
+
+// cacheResponseWriter sets Cache-Control once the status code is known.
+type cacheResponseWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (cw *cacheResponseWriter) WriteHeader(code int) {
+	if !cw.wroteHeader && code >= http.StatusOK {
+		cw.wroteHeader = true
+		policy := cw.policy
+		if code >= http.StatusBadRequest {
+			policy = "no-store"
+		}
+		cw.Header().Set("Cache-Control", policy)
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheResponseWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *cacheResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// loggingResponseWriter is a custom http.ResponseWriter to capture the status
+// code and the size of the body.
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	written    int64
 }
 
 // WriteHeader captures the status code before calling the underlying WriteHeader.
@@ -34,28 +82,105 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+func (lrw *loggingResponseWriter) Write(p []byte) (int, error) {
+	n, err := lrw.ResponseWriter.Write(p)
+	lrw.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
+type requestIDKey struct{}
+
+// requestID returns the id of the request handled with ctx.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts the ids set by a proxy in front of the server.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
 // loggingMiddleware wraps an http.Handler to add logging functionality.
 func loggingMiddleware(next http.Handler) http.Handler {
 	return loggingMiddlewareFunc(next.ServeHTTP)
 }
 
+// loggingMiddlewareFunc tags the request with an id, echoed in X-Request-Id,
+// then writes its access log line and records its metrics.
 func loggingMiddlewareFunc(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now() // Record start time
 
+		id := r.Header.Get("X-Request-Id")
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-Id", id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
 		// Create a custom ResponseWriter to capture status code
 		lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK} // Default to 200 OK
 
 		// Serve the request using the next handler in the chain
+		requestsInFlight.Add(1)
+		defer requestsInFlight.Add(-1)
 		next(lrw, r)
 
-		// Log the access details
-		log.Printf("%s %s \"%s\" %d %s",
-			r.RemoteAddr,       // Client IP address and port
-			r.Method,           // HTTP method (GET, POST, etc.)
-			r.URL.RequestURI(), // Requested URI (including query string)
-			lrw.statusCode,     // HTTP status code
-			time.Since(start),  // Request duration
+		// the mux sets the pattern, it keeps the label count bounded.
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		elapsed := time.Since(start)
+		requestsTotal.Inc(route, r.Method, strconv.Itoa(lrw.statusCode))
+		requestDuration.Observe(elapsed.Seconds(), route, r.Method)
+
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("request_id", id),
+			slog.String("remote", r.RemoteAddr),
+			slog.String("method", r.Method),
+			slog.String("uri", r.URL.RequestURI()),
+			slog.String("route", route),
+			slog.Int("status", lrw.statusCode),
+			slog.Int64("bytes", lrw.written),
+			slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+			slog.String("user_agent", r.UserAgent()),
 		)
 	}
 }
+
+// corsMiddleware lets the pages served from origins call the api, "*"
+// allows any origin.
+func corsMiddleware(origins []string, next http.Handler) http.Handler {
+	return corsMiddlewareFunc(origins, next.ServeHTTP)
+}
+
+func corsMiddlewareFunc(origins []string, next http.HandlerFunc) http.HandlerFunc {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!allowed["*"] && !allowed[origin]) {
+			next(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Content-Range, Retry-After, Deprecation, Link")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Range, If-None-Match, Craft-word, Craft-ipa, Craft-region")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"ok", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("sound")) }, soundCache},
+		{"not modified", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotModified) }, soundCache},
+		{"not found", func(w http.ResponseWriter, _ *http.Request) { http.NotFound(w, nil) }, "no-store"},
+		{"upstream down", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) }, "no-store"},
+	} {
+		w := httptest.NewRecorder()
+		cacheMiddleware(soundCache, tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/pronunciations", nil))
+		if got := w.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	called := false
+	h := corsMiddleware([]string{"https://spaced.example/"}, http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, "/api/cards", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		r.Header.Set("Access-Control-Request-Headers", "authorization,content-type")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := preflight("https://spaced.example")
+	if w.Code != http.StatusNoContent || called || w.Header().Get("Access-Control-Allow-Origin") != "https://spaced.example" {
+		t.Fatalf("got %d, handler called %v, headers %v", w.Code, called, w.Header())
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, http.MethodPost) {
+		t.Errorf("POST not allowed: %q", methods)
+	}
+	allowed := map[string]bool{}
+	for header := range strings.SplitSeq(w.Header().Get("Access-Control-Allow-Headers"), ",") {
+		allowed[strings.ToLower(strings.TrimSpace(header))] = true
+	}
+	for _, header := range []string{"authorization", "content-type"} {
+		if !allowed[header] {
+			t.Errorf("%s not allowed: %q", header, w.Header().Get("Access-Control-Allow-Headers"))
+		}
+	}
+
+	if w := preflight("https://other.example"); w.Header().Get("Access-Control-Allow-Origin") != "" || !called {
+		t.Errorf("other origin: got headers %v, handler called %v", w.Header(), called)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/utils"
+)
+
+// rateLimiter keeps a token bucket per client IP, every request takes a token
+// and tokens refill at rate per second up to burst. Handlers causing more work
+// than a request take the extra tokens with allow.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	idle    time.Duration
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		idle:    10 * time.Minute,
+		buckets: map[string]*bucket{},
+		swept:   time.Now(),
+	}
+}
+
+// capacity is the most tokens a client can take at once.
+func (l *rateLimiter) capacity() int { return int(l.burst) }
+
+// allow takes n tokens for client, when there are not enough it takes none
+// and returns how long until there are. n may not exceed the burst.
+func (l *rateLimiter) allow(client string, n int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > l.idle {
+		// forget clients which are back to a full bucket anyway.
+		for key, b := range l.buckets {
+			if now.Sub(b.seen) > l.idle {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, seen: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.seen).Seconds()*l.rate)
+	b.seen = now
+	if cost := float64(n); b.tokens < cost {
+		return false, time.Duration((cost - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens -= float64(n)
+	return true, 0
+}
+
+// rateLimitMiddleware answers 429 with a Retry-After header to the clients
+// which ran out of tokens.
+func rateLimitMiddleware(l *rateLimiter, next http.Handler) http.Handler {
+	return rateLimitMiddlewareFunc(l, next.ServeHTTP)
+}
+
+func rateLimitMiddlewareFunc(l *rateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.allow(clientIP(r), 1, time.Now()); !ok {
+			tooManyRequests(w, wait)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	utils.SError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, retry later")
+}
+
+// clientIP drops the port from the remote address so every connection of a
+// client shares the same bucket.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
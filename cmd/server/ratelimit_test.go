@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBucket(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := time.Now()
+
+	// a new client starts with a full bucket.
+	for i := range 3 {
+		if ok, _ := l.allow("a", 1, now); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := l.allow("a", 1, now)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("empty bucket: got %v %s, want a 500ms wait", ok, wait)
+	}
+	if ok, _ := l.allow("b", 3, now); !ok {
+		t.Error("other clients have their own bucket")
+	}
+
+	// two tokens per second.
+	if ok, _ := l.allow("a", 1, now.Add(500*time.Millisecond)); !ok {
+		t.Error("refilled token refused")
+	}
+	if ok, wait := l.allow("a", 2, now.Add(time.Second)); ok || wait != 500*time.Millisecond {
+		t.Errorf("one token for two: got %v %s, want a 500ms wait", ok, wait)
+	}
+	// refused requests take nothing, and the bucket never holds more than
+	// the burst.
+	if ok, _ := l.allow("a", 3, now.Add(time.Hour)); !ok {
+		t.Error("full bucket refused the burst")
+	}
+	if ok, _ := l.allow("a", 1, now.Add(time.Hour)); ok {
+		t.Error("the bucket went over the burst")
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	now := time.Now()
+	l.allow("idle", 1, now)
+	l.allow("active", 1, now)
+
+	l.allow("active", 1, now.Add(l.idle/2))
+	if len(l.buckets) != 2 {
+		t.Fatalf("swept before the idle period, %d buckets left", len(l.buckets))
+	}
+	l.allow("active", 1, now.Add(l.idle+time.Second))
+	if _, ok := l.buckets["idle"]; ok || len(l.buckets) != 1 {
+		t.Errorf("got buckets %v, want only the active client", l.buckets)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	l := newRateLimiter(0.5, 1)
+	h := rateLimitMiddleware(l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	get := func(remote string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/dictionary", nil)
+		r.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := get("192.0.2.1:1000"); w.Code != http.StatusOK {
+		t.Fatalf("first request: got %d", w.Code)
+	}
+	// another connection of the same client shares the bucket.
+	w := get("192.0.2.1:2000")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("got %d, Retry-After %q, want 429 after 2s", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := get("192.0.2.2:1000"); w.Code != http.StatusOK {
+		t.Errorf("other client: got %d", w.Code)
+	}
+}
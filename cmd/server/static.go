@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/site"
+)
+
+// staticHandler serves the files of fsys, picked by site, with content hash
+// ETags, a cache policy per kind of file and the precompressed .br/.gz
+// variant of a file when the client accepts it.
+type staticHandler struct {
+	fsys  fs.FS
+	site  *site.Site
+	cache cacheConfig
+
+	mu    sync.Mutex
+	etags map[string]etag
+}
+
+type etag struct {
+	modTime time.Time
+	size    int64
+	value   string
+}
+
+// encodings are tried in order of preference.
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// hashedName matches file names carrying their content hash, like
+// app.3f2a9c1d07.wasm as go generate names them, which never change and can
+// be cached forever.
+var hashedName = regexp.MustCompile(`\.[0-9a-f]{8,}\.[a-z0-9]+$`)
+
+func newStaticHandler(fsys fs.FS, rewrites []site.Rewrite, cache cacheConfig) (*staticHandler, error) {
+	s, err := site.New(fsys, rewrites)
+	if err != nil {
+		return nil, err
+	}
+	return &staticHandler{
+		fsys:  fsys,
+		site:  s,
+		cache: cache,
+		etags: map[string]etag{},
+	}, nil
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name, err := h.site.Resolve(r.URL.Path)
+	switch {
+	case errors.Is(err, fs.ErrInvalid):
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	case err != nil:
+		h.notFound(w, r)
+		return
+	}
+	f, info, err := h.open(name)
+	if err != nil {
+		h.notFound(w, r)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	w.Header().Set("Cache-Control", h.policy(name))
+	w.Header().Add("Vary", "Accept-Encoding")
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	content, contentInfo, suffix := f, info, ""
+	for _, enc := range encodings {
+		if !acceptsEncoding(r, enc.name) {
+			continue
+		}
+		encoded, encodedInfo, err := h.open(name + enc.ext)
+		if err != nil || encodedInfo.IsDir() {
+			if encoded != nil {
+				_ = encoded.Close()
+			}
+			continue
+		}
+		defer func() { _ = encoded.Close() }()
+		content, contentInfo, suffix = encoded, encodedInfo, enc.ext
+		w.Header().Set("Content-Encoding", enc.name)
+		break
+	}
+
+	seeker, ok := content.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			http.Error(w, "failed to read file", http.StatusInternalServerError)
+			return
+		}
+		seeker = bytes.NewReader(data)
+	}
+	value, err := h.etag(name+suffix, seeker, contentInfo)
+	if err != nil {
+		http.Error(w, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+	// the encodings of a file are different representations, so their tags
+	// must differ too.
+	w.Header().Set("ETag", fmt.Sprintf(`"%s%s"`, value, strings.ReplaceAll(suffix, ".", "-")))
+	lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	http.ServeContent(lrw, r, name, contentInfo.ModTime(), seeker)
+	switch lrw.statusCode {
+	case http.StatusNotModified:
+		staticResponses.Inc("hit")
+	case http.StatusOK, http.StatusPartialContent:
+		staticResponses.Inc("miss")
+	}
+}
+
+// notFound answers with the 404 page of the site when it has one.
+func (h *staticHandler) notFound(w http.ResponseWriter, r *http.Request) {
+	page, err := fs.ReadFile(h.fsys, site.NotFoundPage)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(page)
+	}
+}
+
+func (h *staticHandler) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// policy picks the Cache-Control of a file: nothing is cached in dev mode,
+// hashed assets are immutable, pages and the deck are revalidated on every
+// load, the rest is kept for cache.maxAge before being revalidated.
+func (h *staticHandler) policy(name string) string {
+	ext := path.Ext(name)
+	switch {
+	case h.cache.Disabled:
+		return "no-cache, no-store, must-revalidate"
+	case hashedName.MatchString(name):
+		return "public, max-age=31536000, immutable"
+	case ext == "" || ext == ".html" || ext == ".json":
+		return "no-cache"
+	case h.cache.MaxAge.Duration <= 0:
+		return "no-cache"
+	default:
+		return fmt.Sprintf("public, max-age=%d, must-revalidate", int(h.cache.MaxAge.Seconds()))
+	}
+}
+
+// etag hashes the content of f once per modification, f is rewound after.
+func (h *staticHandler) etag(key string, f io.ReadSeeker, info fs.FileInfo) (string, error) {
+	h.mu.Lock()
+	cached, ok := h.etags[key]
+	h.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		etagCache.Inc("hit")
+		return cached.value, nil
+	}
+	etagCache.Inc("miss")
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(hash.Sum(nil))[:16]
+
+	h.mu.Lock()
+	h.etags[key] = etag{modTime: info.ModTime(), size: info.Size(), value: value}
+	h.mu.Unlock()
+	return value, nil
+}
+
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(accepted), ";")
+		if strings.TrimSpace(name) != encoding {
+			continue
+		}
+		// q=0 means the client refuses it.
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
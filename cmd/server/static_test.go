@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newStatic(t *testing.T, cache cacheConfig) *staticHandler {
+	t.Helper()
+	files := fstest.MapFS{
+		"index.html":                    {Data: []byte("<h1>spaced</h1>")},
+		"style.css":                     {Data: []byte("body {}")},
+		"assets/cards.json":             {Data: []byte("[]")},
+		"assets/app.3f2a9c1d07.wasm":    {Data: []byte("module")},
+		"assets/app.3f2a9c1d07.wasm.br": {Data: []byte("br")},
+		"assets/app.3f2a9c1d07.wasm.gz": {Data: []byte("gz")},
+		"assets/main.0c949f4996.js":     {Data: []byte("main()")},
+	}
+	h, err := newStaticHandler(files, nil, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func serve(h http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestStaticCacheControl(t *testing.T) {
+	h := newStatic(t, cacheConfig{MaxAge: duration{time.Hour}})
+	dev := newStatic(t, cacheConfig{Disabled: true, MaxAge: duration{time.Hour}})
+	revalidate := newStatic(t, cacheConfig{})
+	for _, tt := range []struct {
+		name    string
+		handler *staticHandler
+		path    string
+		want    string
+	}{
+		{"page", h, "/", "no-cache"},
+		{"deck", h, "/assets/cards.json", "no-cache"},
+		{"asset", h, "/style.css", "public, max-age=3600, must-revalidate"},
+		{"asset without max age", revalidate, "/style.css", "no-cache"},
+		{"hashed module", h, "/assets/app.3f2a9c1d07.wasm", "public, max-age=31536000, immutable"},
+		{"hashed script", revalidate, "/assets/main.0c949f4996.js", "public, max-age=31536000, immutable"},
+		{"dev page", dev, "/", "no-cache, no-store, must-revalidate"},
+		{"dev hashed module", dev, "/assets/app.3f2a9c1d07.wasm", "no-cache, no-store, must-revalidate"},
+	} {
+		w := serve(tt.handler, tt.path, nil)
+		if got := w.Header().Get("Cache-Control"); w.Code != http.StatusOK || got != tt.want {
+			t.Errorf("%s: got %d %q, want %q", tt.name, w.Code, got, tt.want)
+		}
+	}
+}
+
+func TestStaticETag(t *testing.T) {
+	h := newStatic(t, cacheConfig{})
+	w := serve(h, "/style.css", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) != 18 || w.Body.String() != "body {}" {
+		t.Fatalf("got %d %q %q", w.Code, etag, w.Body)
+	}
+	if again := serve(h, "/style.css", nil).Header().Get("ETag"); again != etag {
+		t.Errorf("etag changed from %s to %s", etag, again)
+	}
+
+	w = serve(h, "/style.css", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("matching etag: got %d %q, want 304", w.Code, w.Body)
+	}
+	w = serve(h, "/style.css", http.Header{"If-None-Match": {`"stale"`}})
+	if w.Code != http.StatusOK {
+		t.Errorf("stale etag: got %d, want 200", w.Code)
+	}
+}
+
+func TestStaticEncoding(t *testing.T) {
+	h := newStatic(t, cacheConfig{})
+	module := "/assets/app.3f2a9c1d07.wasm"
+	etags := map[string]bool{}
+	for _, tt := range []struct {
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"", "", "module"},
+		{"gzip", "gzip", "gz"},
+		{"gzip, br", "br", "br"},
+		{"br;q=0, gzip", "gzip", "gz"},
+		{"deflate", "", "module"},
+	} {
+		w := serve(h, module, http.Header{"Accept-Encoding": {tt.accept}})
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding || w.Body.String() != tt.body {
+			t.Errorf("accept %q: got %q %q, want %q %q", tt.accept, got, w.Body, tt.encoding, tt.body)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("accept %q: got Vary %q", tt.accept, got)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/wasm" {
+			t.Errorf("accept %q: got Content-Type %q", tt.accept, got)
+		}
+		etags[w.Header().Get("ETag")] = true
+	}
+	// identity, gzip and br.
+	if len(etags) != 3 {
+		t.Errorf("expected an etag per encoding, got %v", etags)
+	}
+
+	// a file without precompressed copies is served as is.
+	if w := serve(h, "/style.css", http.Header{"Accept-Encoding": {"br, gzip"}}); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("style.css: got Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the subset of TOML a config file needs: comments,
+// [tables] and [nested.tables], key = value pairs where value is a basic or
+// literal string, an integer, a float, a boolean or a single line array of
+// those.
+func parseTOML(src string) (map[string]any, error) {
+	root := map[string]any{}
+	table := root
+	for n, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", n+1, fmt.Sprintf(format, args...))
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fail("invalid table header %q", line)
+			}
+			table = root
+			for _, key := range strings.Split(line[1:len(line)-1], ".") {
+				key = strings.TrimSpace(key)
+				if key == "" {
+					return nil, fail("empty table name in %q", line)
+				}
+				child, ok := table[key].(map[string]any)
+				if !ok {
+					if _, exists := table[key]; exists {
+						return nil, fail("%s is already a value", key)
+					}
+					child = map[string]any{}
+					table[key] = child
+				}
+				table = child
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fail("expected key = value, got %q", line)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		if key == "" {
+			return nil, fail("empty key")
+		}
+		if _, exists := table[key]; exists {
+			return nil, fail("duplicate key %s", key)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fail("%s: %v", key, err)
+		}
+		table[key] = value
+	}
+	return root, nil
+}
+
+func parseTOMLValue(raw string) (any, error) {
+	switch {
+	case raw == "":
+		return nil, fmt.Errorf("missing value")
+	case raw == "true", raw == "false":
+		return raw == "true", nil
+	case raw[0] == '"':
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid or unterminated string %s", raw)
+		}
+		return s, nil
+	case raw[0] == '\'':
+		if len(raw) < 2 || raw[len(raw)-1] != '\'' {
+			return nil, fmt.Errorf("unterminated string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw[0] == '[':
+		if raw[len(raw)-1] != ']' {
+			return nil, fmt.Errorf("unterminated array %s", raw)
+		}
+		items := []any{}
+		for _, item := range splitTOMLArray(raw[1 : len(raw)-1]) {
+			value, err := parseTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	}
+
+	number := strings.ReplaceAll(raw, "_", "")
+	if i, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported value %s", raw)
+}
+
+// splitTOMLArray splits the items of an array on the commas outside strings.
+func splitTOMLArray(src string) []string {
+	items := []string{}
+	start := 0
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, src[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, src[start:])
+
+	trimmed := items[:0]
+	for _, item := range items {
+		// a trailing comma is allowed.
+		if item = strings.TrimSpace(item); item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return trimmed
+}
+
+// stripComment drops a # comment which is not inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
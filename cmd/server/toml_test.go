@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOMLExample(t *testing.T) {
+	data, err := os.ReadFile("spaced.example.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseTOML(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"addr":      ":8080",
+		"staticDir": "ui",
+		"dataDir":   "ui/assets",
+		"provider":  "cambridge",
+		"rewrites":  "vercel.json",
+		"dev":       false,
+		"tls":       map[string]any{"cert": "", "key": ""},
+		"cache":     map[string]any{"disabled": false, "maxAge": "0s"},
+		"cors":      map[string]any{"origins": []any{}},
+		"log":       map[string]any{"format": "text"},
+		"timeouts": map[string]any{
+			"readHeader": "5s",
+			"read":       "15s",
+			"write":      "60s",
+			"idle":       "2m",
+			"shutdown":   "30s",
+		},
+		"rateLimit": map[string]any{"rate": 5.0, "burst": int64(20)},
+		"cards":     map[string]any{"token": ""},
+		"upstream": map[string]any{
+			"concurrency":      int64(8),
+			"queue":            int64(64),
+			"breakerThreshold": int64(5),
+			"breakerCooldown":  "30s",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+
+	// the example documents every setting.
+	defaults, err := json.Marshal(defaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := map[string]any{}
+	if err := json.Unmarshal(defaults, &settings); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range missingKeys(settings, got, "") {
+		t.Errorf("spaced.example.toml misses %s", key)
+	}
+
+	cfg := defaultConfig()
+	if err := cfg.readFile("spaced.example.toml"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StaticDir != "ui" || cfg.RateLimit.Burst != 20 || cfg.Timeouts.Idle.Minutes() != 2 {
+		t.Errorf("example read as %+v", cfg)
+	}
+}
+
+// missingKeys lists the keys of want, nested with dots, which got lacks.
+func missingKeys(want, got map[string]any, prefix string) []string {
+	missing := []string{}
+	for key, value := range want {
+		inner, ok := got[key]
+		if !ok {
+			missing = append(missing, prefix+key)
+			continue
+		}
+		if table, ok := value.(map[string]any); ok {
+			innerTable, _ := inner.(map[string]any)
+			missing = append(missing, missingKeys(table, innerTable, prefix+key+".")...)
+		}
+	}
+	return missing
+}
+
+func TestParseTOML(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want map[string]any
+	}{
+		{`a = 'C:\dir' # comment`, map[string]any{"a": `C:\dir`}},
+		{`a = "x # y"`, map[string]any{"a": "x # y"}},
+		{`a = "tab\t"`, map[string]any{"a": "tab\t"}},
+		{`a = 1_000`, map[string]any{"a": int64(1000)}},
+		{`a = -0.5`, map[string]any{"a": -0.5}},
+		{`a = true`, map[string]any{"a": true}},
+		{`a = ["x, y", 'z', 1,]`, map[string]any{"a": []any{"x, y", "z", int64(1)}}},
+		{"[a.b]\nc = 1\n[a]\nd = 2", map[string]any{"a": map[string]any{"b": map[string]any{"c": int64(1)}, "d": int64(2)}}},
+		{`"a" = 1`, map[string]any{"a": int64(1)}},
+	} {
+		got, err := parseTOML(tt.src)
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unterminated basic string", `addr = ":8080`, "line 1: addr: invalid or unterminated string"},
+		{"unterminated literal string", "\naddr = ':8080", "line 2: addr: unterminated string"},
+		{"unterminated array", `origins = ["a"`, "unterminated array"},
+		{"missing value", `addr =`, "missing value"},
+		{"bare word", `addr = localhost`, "unsupported value localhost"},
+		{"no equal sign", `addr`, "expected key = value"},
+		{"empty key", `= 1`, "empty key"},
+		{"duplicate key", "dev = true\ndev = false", "line 2: duplicate key dev"},
+		{"array of tables", `[[timeouts]]`, "invalid table header"},
+		{"unclosed table", `[timeouts`, "invalid table header"},
+		{"empty table name", `[cache.]`, "empty table name"},
+		{"table over a value", "log = 1\n[log]", "log is already a value"},
+	} {
+		_, err := parseTOML(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestReadFileTOMLErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unknown table", "[caches]\ndisabled = true", `unknown field "caches"`},
+		{"unknown key", "[cache]\nmax_age = \"1h\"", `unknown field "max_age"`},
+		{"bad duration", "[timeouts]\nidle = \"2 minutes\"", `in duration "2 minutes"`},
+		{"duration as number", "[timeouts]\nidle = 120", "duration must be a string"},
+		{"wrong type", `dev = "yes"`, "cannot unmarshal string"},
+	} {
+		path := filepath.Join(t.TempDir(), "spaced.toml")
+		if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg := defaultConfig()
+		err := cfg.readFile(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/deck"
+)
+
+const usage = `usage: spaced <command> [flags]
+
+commands:
+  add   look words up in the dictionary and append them to the deck
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "add":
+		if err := add(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "spaced add:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// add implements `spaced add [-deck path] [-region us] [-first] [-f file] word...`.
+func add(args []string) error {
+	flags := flag.NewFlagSet("add", flag.ExitOnError)
+	deckPath := flags.String("deck", "./ui/assets/cards.json", "deck file to append to")
+	region := flags.String("region", "us", "preferred pronunciation region")
+	first := flags.Bool("first", false, "pick the first sense instead of asking")
+	wordsFile := flags.String("f", "", "file with one word per line")
+	_ = flags.Parse(args)
+
+	words := flags.Args()
+	if *wordsFile != "" {
+		fileBytes, err := os.ReadFile(*wordsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read words file: %w", err)
+		}
+		for line := range strings.Lines(string(fileBytes)) {
+			if word := strings.TrimSpace(line); word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+	if len(words) == 0 {
+		return errors.New("no words given")
+	}
+
+	d, err := deck.Load(*deckPath)
+	if err != nil {
+		return err
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	added := 0
+	for _, word := range words {
+		if d.Has(word) {
+			fmt.Printf("%s: already in deck, skipping\n", word)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		candidates, err := deck.Suggest(ctx, word, *region)
+		cancel()
+		if err != nil {
+			fmt.Printf("%s: %v, skipping\n", word, err)
+			continue
+		}
+		if len(candidates) == 0 {
+			fmt.Printf("%s: no definition found, skipping\n", word)
+			continue
+		}
+
+		card := candidates[0]
+		if !*first && len(candidates) > 1 {
+			picked, ok := pick(stdin, word, candidates)
+			if !ok {
+				fmt.Printf("%s: skipped\n", word)
+				continue
+			}
+			card = picked
+		}
+
+		if err := d.Add(card); err != nil {
+			fmt.Printf("%s: %v, skipping\n", word, err)
+			continue
+		}
+		added++
+		fmt.Printf("%s: added /%s/ %s\n", card.Word, card.IPA, card.Definition)
+	}
+
+	if added == 0 {
+		fmt.Println("nothing to add")
+		return nil
+	}
+	if err := d.Save(*deckPath); err != nil {
+		return err
+	}
+	fmt.Printf("🚀 added %d cards, deck has %d cards\n", added, d.Len())
+	return nil
+}
+
+// pick asks the user which sense of word to keep, 0 skips the word.
+func pick(stdin *bufio.Reader, word string, candidates []model.Card) (model.Card, bool) {
+	fmt.Printf("%s:\n", word)
+	for i, card := range candidates {
+		fmt.Printf("  %d) %s\n", i+1, card.Definition)
+		if card.Example != "" {
+			fmt.Printf("     %q\n", card.Example)
+		}
+	}
+	for {
+		fmt.Printf("pick a sense [1-%d, 0 to skip]: ", len(candidates))
+		line, err := stdin.ReadString('\n')
+		if err != nil {
+			return model.Card{}, false
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(line))
+		if err != nil || choice < 0 || choice > len(candidates) {
+			continue
+		}
+		if choice == 0 {
+			return model.Card{}, false
+		}
+		return candidates[choice-1], true
+	}
+}
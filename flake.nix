@@ -32,6 +32,7 @@
             direnv
             fd
             ripgrep
+            brotli
             uutils-coreutils-noprefix
 
             # Code formatter stuffs
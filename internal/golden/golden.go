@@ -0,0 +1,47 @@
+// Package golden compares the output of the scraper tests with the golden
+// files saved next to their pages in testdata.
+//
+//	go test ./src/html ./src/dictionary -update  # rewrite the golden files
+package golden
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// Run calls render in a sub test for every testdata/<page>.html, with the
+// path of the page without its extension, and compares what it returns
+// with testdata/<page>.golden.
+func Run(t *testing.T, render func(t *testing.T, name string) []byte) {
+	t.Helper()
+	pages, err := filepath.Glob("testdata/*.html")
+	if err != nil || len(pages) == 0 {
+		t.Fatalf("no pages found in testdata: %v", err)
+	}
+	for _, page := range pages {
+		name := strings.TrimSuffix(page, ".html")
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			got := render(t, name)
+			golden := name + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(want, got) {
+				t.Errorf("%s differs, got:\n%s", golden, got)
+			}
+		})
+	}
+}
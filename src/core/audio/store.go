@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+// keyPrefix namespaces the audio store inside localStorage.
+const keyPrefix = "audio:"
+
+// indexKey holds the entries of the store, least recently used first.
+const indexKey = keyPrefix + "index"
+
+// DefaultLimit is the budget of the sounds in the storage, in encoded bytes.
+// localStorage holds about 5 MB for the whole origin, the cards and records
+// need the rest.
+const DefaultLimit = 2 << 20
+
+// Key returns the content address of a sound, cards sharing the same
+// pronunciation share the same entry.
+func Key(sound []byte) string {
+	sum := sha256.Sum256(sound)
+	return keyPrefix + hex.EncodeToString(sum[:])
+}
+
+type entry struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// Store keeps sounds in the storage of the page within a budget, the least
+// recently used ones are evicted to make room for new ones.
+type Store struct {
+	storage crafter.Storage
+	limit   int
+
+	mu sync.Mutex
+}
+
+// NewStore returns the store of the sounds in storage, holding up to limit
+// encoded bytes.
+func NewStore(storage crafter.Storage, limit int) *Store {
+	return &Store{storage: storage, limit: limit}
+}
+
+func (s *Store) index() []entry {
+	var index []entry
+	if err := crafter.StorageGetItem(s.storage, indexKey, &index); err != nil {
+		return nil
+	}
+	return index
+}
+
+func (s *Store) saveIndex(index []entry) {
+	if err := crafter.StorageSetItem(s.storage, indexKey, index); err != nil {
+		fmt.Println("failed to save audio index:", err)
+	}
+}
+
+// Put stores the sound and returns its key. localStorage only holds strings,
+// so the sound is kept base64 encoded. The least recently used sounds are
+// evicted while the budget or the quota of the storage is exceeded.
+func (s *Store) Put(sound []byte) (string, error) {
+	key := Key(sound)
+	encoded := base64.StdEncoding.EncodeToString(sound)
+	if len(encoded) > s.limit {
+		return "", fmt.Errorf("failed to store sound: %d bytes exceed the limit of %d", len(encoded), s.limit)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := slices.DeleteFunc(s.index(), func(e entry) bool { return e.Key == key })
+	size := len(encoded)
+	for _, e := range index {
+		size += e.Size
+	}
+	for len(index) > 0 && size > s.limit {
+		size -= index[0].Size
+		index = s.evict(index)
+	}
+	for {
+		err := crafter.StorageSetItem(s.storage, key, encoded)
+		if err == nil {
+			break
+		}
+		if len(index) == 0 {
+			s.saveIndex(index)
+			return "", fmt.Errorf("failed to store sound: %w", err)
+		}
+		// the storage is full with other items, make room anyway.
+		index = s.evict(index)
+	}
+	s.saveIndex(append(index, entry{Key: key, Size: len(encoded)}))
+	return key, nil
+}
+
+// evict removes the least recently used sound of index.
+func (s *Store) evict(index []entry) []entry {
+	if err := s.storage.RemoveItem(index[0].Key); err != nil {
+		fmt.Println("failed to evict sound", index[0].Key, err)
+	}
+	return index[1:]
+}
+
+// Get returns the sound stored under key, which becomes the most recently
+// used one.
+func (s *Store) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := s.index()
+	i := slices.IndexFunc(index, func(e entry) bool { return e.Key == key })
+
+	var sound64 string
+	if err := crafter.StorageGetItem(s.storage, key, &sound64); err != nil {
+		if i >= 0 {
+			s.saveIndex(slices.Delete(index, i, i+1))
+		}
+		return nil, fmt.Errorf("failed to load sound %s: %w", key, err)
+	}
+	sound, err := base64.StdEncoding.DecodeString(sound64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sound %s: %w", key, err)
+	}
+	switch {
+	case i < 0:
+		// stored before the index, it is evicted like the others from now.
+		s.saveIndex(append(index, entry{Key: key, Size: len(sound64)}))
+	case i < len(index)-1:
+		e := index[i]
+		s.saveIndex(append(slices.Delete(index, i, i+1), e))
+	}
+	return sound, nil
+}
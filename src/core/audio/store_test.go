@@ -0,0 +1,119 @@
+package audio_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/core/audio"
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+// quota is a storage holding up to size bytes of values, like localStorage.
+type quota struct {
+	items map[string]string
+	size  int
+}
+
+func (q *quota) used() int {
+	used := 0
+	for _, v := range q.items {
+		used += len(v)
+	}
+	return used
+}
+
+func (q *quota) GetItem(key string) (string, bool) {
+	v, ok := q.items[key]
+	return v, ok
+}
+
+func (q *quota) SetItem(key, value string) error {
+	if q.used()-len(q.items[key])+len(value) > q.size {
+		return errors.New("quota exceeded")
+	}
+	q.items[key] = value
+	return nil
+}
+
+func (q *quota) RemoveItem(key string) error {
+	delete(q.items, key)
+	return nil
+}
+
+// sounds encode to 12 bytes each.
+var sounds = [][]byte{[]byte("sound-a!!"), []byte("sound-b!!"), []byte("sound-c!!")}
+
+func stored(t *testing.T, store *audio.Store, sound []byte) bool {
+	t.Helper()
+	got, err := store.Get(audio.Key(sound))
+	if err != nil {
+		return false
+	}
+	if !bytes.Equal(got, sound) {
+		t.Fatalf("expected %q, got %q", sound, got)
+	}
+	return true
+}
+
+// TestStoreEvicts keeps the most recently used sounds within the limit.
+func TestStoreEvicts(t *testing.T) {
+	store := audio.NewStore(crafter.NewFake("/").Storage(), 24)
+	for _, sound := range sounds[:2] {
+		if _, err := store.Put(sound); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a is used again, b is the least recently used one.
+	if !stored(t, store, sounds[0]) {
+		t.Fatal("expected a stored")
+	}
+	if _, err := store.Put(sounds[2]); err != nil {
+		t.Fatal(err)
+	}
+	if !stored(t, store, sounds[0]) || stored(t, store, sounds[1]) || !stored(t, store, sounds[2]) {
+		t.Error("expected b evicted")
+	}
+	if _, err := audio.NewStore(crafter.NewFake("/").Storage(), 8).Put(sounds[0]); err == nil {
+		t.Error("expected a sound over the limit refused")
+	}
+}
+
+// TestStoreQuota evicts sounds when the storage is full before the limit.
+func TestStoreQuota(t *testing.T) {
+	storage := &quota{items: map[string]string{}, size: 1 << 20}
+	store := audio.NewStore(storage, 1<<20)
+	for _, sound := range sounds[:2] {
+		if _, err := store.Put(sound); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the third sound does not fit beside the first two.
+	storage.size = storage.used() + 10
+	if _, err := store.Put(sounds[2]); err != nil {
+		t.Fatal(err)
+	}
+	if stored(t, store, sounds[0]) || !stored(t, store, sounds[1]) || !stored(t, store, sounds[2]) {
+		t.Error("expected a evicted")
+	}
+}
+
+// TestStoreUnindexed evicts the sounds stored before the index too.
+func TestStoreUnindexed(t *testing.T) {
+	storage := crafter.NewFake("/").Storage()
+	if err := crafter.StorageSetItem(storage, audio.Key(sounds[0]), "c291bmQtYSEh"); err != nil {
+		t.Fatal(err)
+	}
+	store := audio.NewStore(storage, 24)
+	if !stored(t, store, sounds[0]) {
+		t.Fatal("expected a stored")
+	}
+	for _, sound := range sounds[1:] {
+		if _, err := store.Put(sound); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stored(t, store, sounds[0]) {
+		t.Error("expected a evicted")
+	}
+}
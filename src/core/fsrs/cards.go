@@ -1,5 +1,3 @@
-//go:build js && wasm
-
 package fsrs
 
 import (
@@ -31,3 +29,29 @@ func (a Cards) String() string {
 	}
 	return strings.Join(words, ",")
 }
+
+// Key is the form under which a word is unique in a deck.
+func Key(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+// Merge appends the incoming cards whose word is not in cards yet, existing
+// cards keep their progress. It returns the merged cards and how many were
+// added.
+func Merge(cards []*model.Card, incoming []*model.Card) ([]*model.Card, int) {
+	seen := make(map[string]bool, len(cards))
+	for _, card := range cards {
+		seen[Key(card.Word)] = true
+	}
+	added := 0
+	for _, card := range incoming {
+		if seen[Key(card.Word)] {
+			continue
+		}
+		seen[Key(card.Word)] = true
+		card.ID = len(cards)
+		cards = append(cards, card)
+		added++
+	}
+	return cards, added
+}
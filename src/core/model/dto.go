@@ -35,6 +35,13 @@ type Card struct {
 	State fsrs.State `json:"state"`
 	// The timestamp of the last review.
 	LastReview time.Time `json:"last_review"`
+
+	// Audio maps a region (us, uk) to the key of the fetched pronunciation in
+	// the audio store, so each word is downloaded once.
+	Audio map[string]string `json:"audio,omitempty"`
+
+	// Suspended cards are left out of the sessions.
+	Suspended bool `json:"suspended,omitempty"`
 }
 
 func (c *Card) ToFsrsCard() fsrs.Card {
@@ -64,7 +71,17 @@ func (c *Card) SyncFromFSRSCard(from fsrs.Card) {
 	c.LastReview = from.LastReview
 }
 
+// Review is an entry of the review log, one per rating.
 type Review struct {
 	CardID int         `json:"cardID"`
 	Rate   fsrs.Rating `json:"rate"`
+	// ShownAt is when the card was shown, zero when it was rated without
+	// being shown. RatedAt is when it was rated.
+	ShownAt time.Time `json:"shownAt"`
+	RatedAt time.Time `json:"ratedAt"`
+	// Duration is how long the answer took, from ShownAt to RatedAt.
+	Duration time.Duration `json:"duration"`
 }
+
+// Timed reports whether the card was shown, so Duration is an answer time.
+func (r Review) Timed() bool { return !r.ShownAt.IsZero() }
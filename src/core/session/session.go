@@ -1,14 +1,11 @@
-//go:build js && wasm
-
 package session
 
 import (
-	"html/template"
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/hnimtadd/spaced/src/core/fsrs"
+	"github.com/hnimtadd/spaced/src/core/model"
 )
 
 type Session struct {
@@ -16,6 +13,10 @@ type Session struct {
 	AgainsID  map[int]bool `json:"againsID"`
 	Looked    map[int]bool `json:"looked"`
 	StartedAt time.Time    `json:"staredAt"`
+	// Shown holds when the cards waiting for a rating were shown.
+	Shown map[int]time.Time `json:"shown"`
+	// Reviews is the log of the ratings given in the session.
+	Reviews []model.Review `json:"reviews"`
 }
 
 func NewSession(cards fsrs.Cards) *Session {
@@ -24,6 +25,7 @@ func NewSession(cards fsrs.Cards) *Session {
 		AgainsID:  map[int]bool{},
 		Looked:    map[int]bool{},
 		StartedAt: time.Now(),
+		Shown:     map[int]time.Time{},
 	}
 }
 
@@ -46,11 +48,18 @@ func (s Session) ShouldStop() bool {
 	return true
 }
 
+// Remaining is the number of cards left to look at, the cards rated again
+// count once more.
+func (s Session) Remaining() int {
+	return len(s.Cards) - len(s.Looked) + len(s.AgainsID)
+}
+
 type Record struct {
-	ID          int       `json:"id"`
-	Cards       []int     `json:"cardIDs"`
-	StartedAt   time.Time `json:"staredAt"`
-	CompletedAt time.Time `json:"completedAt"`
+	ID          int            `json:"id"`
+	Cards       []int          `json:"cardIDs"`
+	StartedAt   time.Time      `json:"staredAt"`
+	CompletedAt time.Time      `json:"completedAt"`
+	Reviews     []model.Review `json:"reviews,omitempty"`
 }
 
 func NewRecordFromSession(session *Session) Record {
@@ -61,55 +70,8 @@ func NewRecordFromSession(session *Session) Record {
 
 	return Record{
 		Cards:       ids,
+		Reviews:     session.Reviews,
 		StartedAt:   session.StartedAt,
 		CompletedAt: time.Now(),
 	}
 }
-
-var sessionTpml = `
-	<div class="bg-white p-4 rounded-lg shadow-md border border-gray-200 space-y-2 cursor-pointer" craft-name="replay" id="stat-{{.ID}}" data="{{.ID}}" craft-input="#stat-{{.ID}}:[data]" craft-trigger="click">
-    <div class="flex items-center justify-between">
-        <span class="font-bold text-gray-700">ID:</span>
-        <span class="font-bold text-gray-700">{{.ID}}</span>
-    </div>
-    <div class="flex items-center justify-between">
-        <span class="font-bold text-gray-700">Status:</span>
-        <span class="px-2 py-1 text-xs font-semibold text-white bg-green-500 rounded-full">Completed</span>
-    </div>
-    <div class="flex items-center justify-between">
-        <span class="font-bold text-gray-700">Card reviewed:</span>
-        <span class="font-normal text-gray-600">{{.CardReviewed}}</span>
-    </div>
-    <div class="flex items-center justify-between">
-        <span class="font-bold text-gray-700">Duration:</span>
-        <span class="font-normal text-gray-600">{{.Duration}}</span>
-    </div>
-    <div class="flex items-center justify-between">
-        <span class="font-bold text-gray-700">Date:</span>
-        <span class="font-normal text-gray-600">{{.Date}}</span>
-    </div>
-</div>
-`
-
-func (s Record) ToHTML() template.HTML {
-	tmpl, err := template.New("session").Parse(sessionTpml)
-	if err != nil {
-		return template.HTML("failed to parse to HTML" + err.Error())
-	}
-	buf := &strings.Builder{}
-	data := struct {
-		Date         string
-		Duration     string
-		CardReviewed int
-		ID           int
-	}{
-		ID:           s.ID,
-		Date:         s.StartedAt.Format(time.DateTime),
-		Duration:     s.CompletedAt.Sub(s.StartedAt).Round(time.Second).String(),
-		CardReviewed: len(s.Cards),
-	}
-	if err := tmpl.Execute(buf, data); err != nil {
-		return template.HTML("failed to execute template" + err.Error())
-	}
-	return template.HTML(buf.String())
-}
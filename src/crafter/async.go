@@ -0,0 +1,143 @@
+//go:build js && wasm
+
+package crafter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall/js"
+)
+
+// HandleAsync registers fn under name like Handle, but fn runs in its own
+// goroutine and the call returns a Promise resolved with the payload or
+// rejected with an Error carrying the code. Blocking work such as HTTP
+// requests must go through it, never through Handle.
+//
+// When the last argument of a call is an AbortSignal, aborting it cancels the
+// ctx of fn and rejects the Promise with the reason of the signal.
+func HandleAsync[Req, Resp any](w *WASM, name string, fn func(context.Context, Req) (Resp, error)) {
+	w.HandleFunc(name, func(_ js.Value, args []js.Value) any {
+		signal := js.Undefined()
+		if n := len(args); n > 0 && isAbortSignal(args[n-1]) {
+			signal, args = args[n-1], args[:n-1]
+		}
+		raw := rawArgs(args)
+		return NewPromise(signal, func(ctx context.Context) (js.Value, error) {
+			env := Invoke(func(req Req) (Resp, error) { return fn(ctx, req) }, raw)
+			if !env.Success {
+				return js.Undefined(), &Error{Code: env.Code, Message: env.Error}
+			}
+			return env.JSValue().Get("payload"), nil
+		})
+	})
+}
+
+// NewPromise runs fn in a new goroutine and returns a Promise settled with its
+// result. signal may be undefined, else aborting it cancels ctx and rejects
+// the Promise right away, fn is expected to return soon after.
+func NewPromise(signal js.Value, fn func(ctx context.Context) (js.Value, error)) js.Value {
+	executor := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		resolve, reject := args[0], args[1]
+		if signal.Truthy() && signal.Get("aborted").Bool() {
+			reject.Invoke(signal.Get("reason"))
+			return nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var once sync.Once
+		settle := func(fn js.Value, v js.Value) {
+			once.Do(func() {
+				cancel()
+				fn.Invoke(v)
+			})
+		}
+
+		stop := func() {}
+		if signal.Truthy() {
+			onAbort := js.FuncOf(func(js.Value, []js.Value) any {
+				settle(reject, signal.Get("reason"))
+				return nil
+			})
+			signal.Call("addEventListener", "abort", onAbort)
+			stop = func() {
+				signal.Call("removeEventListener", "abort", onAbort)
+				onAbort.Release()
+			}
+		}
+
+		go func() {
+			defer stop()
+			v, err := fn(ctx)
+			if err != nil {
+				settle(reject, jsError(err))
+				return
+			}
+			settle(resolve, v)
+		}()
+		return nil
+	})
+	// the executor runs synchronously within the constructor.
+	defer executor.Release()
+	return js.Global().Get("Promise").New(executor)
+}
+
+// Await blocks until promise settles or ctx is done, a rejection is returned
+// as a js.Error. It must not be called from the JS event loop.
+func Await(ctx context.Context, promise js.Value) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	done := make(chan result, 1)
+
+	// the callbacks release themselves once the promise settles, which may
+	// be after ctx is done.
+	var onResolve, onReject js.Func
+	release := func() {
+		onResolve.Release()
+		onReject.Release()
+	}
+	onResolve = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer release()
+		done <- result{v: arg(args, 0)}
+		return nil
+	})
+	onReject = js.FuncOf(func(_ js.Value, args []js.Value) any {
+		defer release()
+		done <- result{err: js.Error{Value: arg(args, 0)}}
+		return nil
+	})
+	promise.Call("then", onResolve, onReject)
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		return js.Undefined(), ctx.Err()
+	}
+}
+
+// jsError converts err into a JS Error with the code of the envelope.
+func jsError(err error) js.Value {
+	var jsErr js.Error
+	if errors.As(err, &jsErr) {
+		return jsErr.Value
+	}
+	env := Fail(err)
+	e := js.Global().Get("Error").New(env.Error)
+	e.Set("code", env.Code)
+	return e
+}
+
+func isAbortSignal(v js.Value) bool {
+	abortSignal := js.Global().Get("AbortSignal")
+	return abortSignal.Truthy() && v.Type() == js.TypeObject && v.InstanceOf(abortSignal)
+}
+
+func arg(args []js.Value, i int) js.Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return js.Undefined()
+}
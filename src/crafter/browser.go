@@ -0,0 +1,81 @@
+//go:build js && wasm
+
+package crafter
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+)
+
+// Browser is the page the module runs in.
+func Browser() Platform { return browser{} }
+
+type browser struct{}
+
+func (browser) Storage() Storage   { return localStorage{} }
+func (browser) Location() Location { return location{} }
+
+func (browser) Dispatch(name string, detail []byte) {
+	target := js.Global()
+	if !target.Get("dispatchEvent").Truthy() {
+		return
+	}
+	init := js.Global().Get("Object").New()
+	init.Set("detail", js.Global().Get("JSON").Call("parse", string(detail)))
+	target.Call("dispatchEvent", js.Global().Get("CustomEvent").New(name, init))
+}
+
+type localStorage struct{}
+
+var errNoLocalStorage = errors.New("localStorage from JS is not truthy")
+
+func (localStorage) value() js.Value { return js.Global().Get("localStorage") }
+
+func (s localStorage) GetItem(key string) (string, bool) {
+	storage := s.value()
+	if !storage.Truthy() {
+		return "", false
+	}
+	item := storage.Call("getItem", key)
+	if item.Type() != js.TypeString {
+		return "", false
+	}
+	return item.String(), true
+}
+
+func (s localStorage) SetItem(key, value string) (err error) {
+	storage := s.value()
+	if !storage.Truthy() {
+		return errNoLocalStorage
+	}
+	// setItem throws when the quota is exceeded.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to push state to localStorage: %v", r)
+		}
+	}()
+	storage.Call("setItem", key, value)
+	return nil
+}
+
+func (s localStorage) RemoveItem(key string) error {
+	storage := s.value()
+	if !storage.Truthy() {
+		return errNoLocalStorage
+	}
+	storage.Call("removeItem", key)
+	return nil
+}
+
+type location struct{}
+
+func (location) Href() string { return js.Global().Get("location").Get("href").String() }
+
+func (location) Assign(url string) { js.Global().Get("location").Call("assign", url) }
+
+func (location) Reload() { js.Global().Get("location").Call("reload") }
+
+func (location) Push(url string) { js.Global().Get("history").Call("pushState", nil, "", url) }
+
+func (location) Replace(url string) { js.Global().Get("history").Call("replaceState", nil, "", url) }
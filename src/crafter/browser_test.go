@@ -0,0 +1,161 @@
+//go:build js && wasm
+
+package crafter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall/js"
+	"testing"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+	crafterhttp "github.com/hnimtadd/spaced/src/crafter/http"
+)
+
+// The tests of this file check crafter against the real syscall/js
+// bindings, in node with the globals of testdata/page.js:
+//
+//	GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" ./src/crafter
+func TestMain(m *testing.M) {
+	page, err := os.ReadFile("testdata/page.js")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	js.Global().Call("eval", string(page))
+	os.Exit(m.Run())
+}
+
+func TestBrowserStorage(t *testing.T) {
+	storage := crafter.Browser().Storage()
+	if err := crafter.StorageSetItem(storage, "records", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	records := []int{}
+	if err := crafter.StorageGetItem(storage, "records", &records); err != nil || len(records) != 2 {
+		t.Fatalf("expected the records stored, got %v %v", records, err)
+	}
+	if err := storage.RemoveItem("records"); err != nil {
+		t.Fatal(err)
+	}
+	if err := crafter.StorageGetItem(storage, "records", &records); !errors.Is(err, crafter.ErrNoItem) {
+		t.Fatalf("expected ErrNoItem, got %v", err)
+	}
+}
+
+func TestBrowserLocation(t *testing.T) {
+	location := crafter.Browser().Location()
+	if href := location.Href(); href != "https://spaced.test/session?id=3" {
+		t.Fatalf("unexpected href %s", href)
+	}
+	location.Assign("/stats")
+	if visit := js.Global().Get("visits").Index(0).String(); visit != "/stats" {
+		t.Errorf("expected a visit of /stats, got %s", visit)
+	}
+	location.Push("/decks/default")
+	location.Replace("/session")
+	entries := js.Global().Get("history").Get("entries")
+	if entries.Length() != 2 || entries.Index(1).String() != "/session" || location.Href() != "/session" {
+		t.Errorf("expected /session to replace /decks/default, got %v", entries)
+	}
+}
+
+type doubleRequest struct {
+	N int
+}
+
+// TestBridge calls the handlers and emits the events through the bridge the
+// way main.js does.
+func TestBridge(t *testing.T) {
+	ctx := context.Background()
+	wasm := crafter.NewWasm()
+	stringify := js.Global().Get("JSON").Get("stringify")
+
+	details := make(chan string, 1)
+	listener := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		details <- stringify.Invoke(args[0].Get("detail")).String()
+		return nil
+	})
+	js.Global().Call("addEventListener", "session:completed", listener)
+	defer listener.Release()
+	defer js.Global().Call("removeEventListener", "session:completed", listener)
+	if err := wasm.Events.Emit("session:completed", map[string]int{"recordID": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-details; got != `{"recordID":1}` {
+		t.Errorf("page listener: got %s", got)
+	}
+
+	received := make(chan string, 1)
+	wasm.Events.On("page:hidden", func(payload json.RawMessage) { received <- string(payload) })
+
+	crafter.Handle(wasm, "double", func(req doubleRequest) (int, error) { return req.N * 2, nil })
+	crafter.HandleAsync(wasm, "wait", func(ctx context.Context, req doubleRequest) (int, error) {
+		select {
+		case <-time.After(time.Duration(req.N) * time.Millisecond):
+			return req.N, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	})
+	crafter.HandleAsync(wasm, "nothing", func(context.Context, struct{}) (any, error) {
+		return nil, nil
+	})
+	crafter.HandleAsync(wasm, "missing", func(context.Context, struct{}) (int, error) {
+		return 0, crafter.Errorf(crafter.CodeNotFound, "no such card")
+	})
+	go func() { _ = wasm.ListenAndServe() }()
+	for !js.Global().Get("wasmBridge").Truthy() {
+		time.Sleep(time.Millisecond)
+	}
+	bridge := js.Global().Get("wasmBridge")
+
+	bridge.Call("emit", "page:hidden", map[string]any{"at": 1})
+	if got := <-received; got != `{"at":1}` {
+		t.Errorf("go subscriber: got %s", got)
+	}
+
+	if got := stringify.Invoke(bridge.Call("double", "21")).String(); got != `{"success":true,"payload":42}` {
+		t.Errorf("handle: got %s", got)
+	}
+	if got := stringify.Invoke(bridge.Call("double")).String(); got != `{"success":false,"error":"expected 1 arguments, got 0","code":"invalid_arguments"}` {
+		t.Errorf("handle arity: got %s", got)
+	}
+
+	if v, err := crafter.Await(ctx, bridge.Call("wait", 5)); err != nil || v.Int() != 5 {
+		t.Errorf("async resolve: got %v %v", v, err)
+	}
+	// zero results resolve to their value, not undefined.
+	if v, err := crafter.Await(ctx, bridge.Call("wait", 0)); err != nil || v.Type() != js.TypeNumber || v.Int() != 0 {
+		t.Errorf("async zero: got %v %v", v, err)
+	}
+	if v, err := crafter.Await(ctx, bridge.Call("nothing")); err != nil || !v.IsNull() {
+		t.Errorf("async nil: got %v %v", v, err)
+	}
+	var jsErr js.Error
+	if _, err := crafter.Await(ctx, bridge.Call("missing")); !errors.As(err, &jsErr) || jsErr.Get("code").String() != crafter.CodeNotFound {
+		t.Errorf("async reject: got %v", err)
+	}
+	controller := js.Global().Get("AbortController").New()
+	promise := bridge.Call("wait", 5000, controller.Get("signal"))
+	controller.Call("abort")
+	if _, err := crafter.Await(ctx, promise); !errors.As(err, &jsErr) || jsErr.Get("name").String() != "AbortError" {
+		t.Errorf("async abort: got %v", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	ctx := context.Background()
+	got, err := crafterhttp.GetJSON[map[string]int](ctx, crafterhttp.DefaultClient, `data:application/json,{"n":1}`)
+	if err != nil || got["n"] != 1 {
+		t.Errorf("fetch json: got %v %v", got, err)
+	}
+	timeout := &crafterhttp.Client{Timeout: time.Nanosecond}
+	if _, err := timeout.Get(ctx, "data:text/plain,slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetch timeout: got %v", err)
+	}
+}
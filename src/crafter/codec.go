@@ -0,0 +1,155 @@
+package crafter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+)
+
+// Error codes of the envelope, JS land switches on them rather than on the
+// messages.
+const (
+	CodeInvalidArgs = "invalid_arguments"
+	CodeNotFound    = "not_found"
+	CodeNotReady    = "not_ready"
+	CodeInternal    = "internal"
+)
+
+// Error is a handler failure carrying a code for JS land.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Message }
+
+// Errorf builds an *Error with a formatted message.
+func Errorf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Envelope is what every typed handler returns to JS land:
+//
+//	{"success": true, "payload": ...}
+//	{"success": false, "error": "...", "code": "..."}
+type Envelope struct {
+	Success bool   `json:"success"`
+	Payload any    `json:"payload"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// MarshalJSON always writes the payload of a success, a nil result reaches
+// JS land as null rather than undefined, and leaves it out of a failure.
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	if e.Success {
+		return json.Marshal(struct {
+			Success bool `json:"success"`
+			Payload any  `json:"payload"`
+		}{e.Success, e.Payload})
+	}
+	return json.Marshal(struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error,omitempty"`
+		Code    string `json:"code,omitempty"`
+	}{e.Success, e.Error, e.Code})
+}
+
+// Ok wraps a successful result.
+func Ok(payload any) Envelope {
+	return Envelope{Success: true, Payload: payload}
+}
+
+// Fail wraps err, errors other than *Error are reported as internal.
+func Fail(err error) Envelope {
+	var e *Error
+	if errors.As(err, &e) {
+		return Envelope{Error: e.Message, Code: e.Code}
+	}
+	return Envelope{Error: err.Error(), Code: CodeInternal}
+}
+
+// Invoke decodes args into Req, runs fn and wraps its result, a panic in fn
+// is turned into an internal error instead of killing the module.
+func Invoke[Req, Resp any](fn func(Req) (Resp, error), args []json.RawMessage) (env Envelope) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("crafter: handler panicked: %v\n%s", r, debug.Stack())
+			env = Fail(Errorf(CodeInternal, "handler panicked: %v", r))
+		}
+	}()
+
+	req, err := DecodeArgs[Req](args)
+	if err != nil {
+		return Fail(err)
+	}
+	resp, err := fn(req)
+	if err != nil {
+		return Fail(err)
+	}
+	return Ok(resp)
+}
+
+// DecodeArgs maps the JSON encoded arguments of a call onto the fields of
+// the struct Req, in order. Fields tagged `crafter:",optional"` may be left
+// out by the caller, the others are required. As craft-input only yields
+// strings, a string argument is also tried as the JSON of its field, so "3"
+// fills an int.
+func DecodeArgs[Req any](args []json.RawMessage) (Req, error) {
+	var req Req
+	v := reflect.ValueOf(&req).Elem()
+	if v.Kind() != reflect.Struct {
+		return req, Errorf(CodeInternal, "request type %s is not a struct", v.Type())
+	}
+
+	fields := []int{}
+	required := 0
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, opts, _ := strings.Cut(field.Tag.Get("crafter"), ","); opts != "optional" {
+			if required < len(fields) {
+				return req, Errorf(CodeInternal, "required field %s follows an optional one", field.Name)
+			}
+			required++
+		}
+		fields = append(fields, i)
+	}
+
+	if len(args) < required || len(args) > len(fields) {
+		want := fmt.Sprint(required)
+		if required != len(fields) {
+			want = fmt.Sprintf("%d to %d", required, len(fields))
+		}
+		return req, Errorf(CodeInvalidArgs, "expected %s arguments, got %d", want, len(args))
+	}
+
+	for i, arg := range args {
+		field := v.Field(fields[i])
+		if err := decodeArg(arg, field.Addr().Interface()); err != nil {
+			return req, Errorf(CodeInvalidArgs, "argument %d (%s): %v", i+1, v.Type().Field(fields[i]).Name, err)
+		}
+	}
+	return req, nil
+}
+
+func decodeArg(arg json.RawMessage, to any) error {
+	err := json.Unmarshal(arg, to)
+	if err == nil {
+		return nil
+	}
+	// "3" for an int or "{...}" for a struct.
+	var s string
+	if bytes.HasPrefix(bytes.TrimSpace(arg), []byte(`"`)) && json.Unmarshal(arg, &s) == nil {
+		if json.Unmarshal([]byte(s), to) == nil {
+			return nil
+		}
+	}
+	return err
+}
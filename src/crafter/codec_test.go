@@ -0,0 +1,101 @@
+package crafter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+type submitRequest struct {
+	CardID int
+	Rating int
+}
+
+type lookupRequest struct {
+	Word   string
+	Region string `crafter:",optional"`
+}
+
+type badRequest struct {
+	Word   string `crafter:",optional"`
+	Region string
+}
+
+// call invokes fn with JSON arguments, the way main.js does, and returns
+// the JSON of its envelope.
+func call[Req, Resp any](t *testing.T, fn func(Req) (Resp, error), args ...string) string {
+	t.Helper()
+	raw := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		raw[i] = json.RawMessage(arg)
+	}
+	data, err := json.Marshal(crafter.Invoke(fn, raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestInvoke checks how the typed handlers decode their arguments and encode
+// their results.
+func TestInvoke(t *testing.T) {
+	submit := func(req submitRequest) (string, error) {
+		if req.CardID == 404 {
+			return "", crafter.Errorf(crafter.CodeNotFound, "no card %d", req.CardID)
+		}
+		return fmt.Sprintf("%d:%d", req.CardID, req.Rating), nil
+	}
+	lookup := func(req lookupRequest) (string, error) {
+		return req.Word + "/" + req.Region, nil
+	}
+	none := func(struct{}) (string, error) { return "ready", nil }
+	failing := func(struct{}) (string, error) { return "", errors.New("disk full") }
+	panicking := func(struct{}) (string, error) { panic("boom") }
+	bad := func(badRequest) (string, error) { return "", nil }
+	notStruct := func(int) (string, error) { return "", nil }
+	zero := func(struct{}) (int, error) { return 0, nil }
+	empty := func(struct{}) ([]string, error) { return nil, nil }
+	nothing := func(struct{}) (any, error) { return nil, nil }
+
+	tcs := []struct {
+		name string
+		call func(t *testing.T, args ...string) string
+		args []string
+		want string
+	}{
+		{"numbers", handler(submit), []string{`3`, `4`}, `{"success":true,"payload":"3:4"}`},
+		{"strings of numbers", handler(submit), []string{`"3"`, `"4"`}, `{"success":true,"payload":"3:4"}`},
+		{"too few", handler(submit), []string{`3`}, `{"success":false,"error":"expected 2 arguments, got 1","code":"invalid_arguments"}`},
+		{"too many", handler(submit), []string{`3`, `4`, `5`}, `{"success":false,"error":"expected 2 arguments, got 3","code":"invalid_arguments"}`},
+		{"wrong type", handler(submit), []string{`3`, `"four"`}, `{"success":false,"error":"argument 2 (Rating): json: cannot unmarshal string into Go value of type int","code":"invalid_arguments"}`},
+		{"coded error", handler(submit), []string{`404`, `1`}, `{"success":false,"error":"no card 404","code":"not_found"}`},
+		{"optional left out", handler(lookup), []string{`"tree"`}, `{"success":true,"payload":"tree/"}`},
+		{"optional given", handler(lookup), []string{`"tree"`, `"uk"`}, `{"success":true,"payload":"tree/uk"}`},
+		{"null", handler(lookup), []string{`null`}, `{"success":true,"payload":"/"}`},
+		{"range", handler(lookup), nil, `{"success":false,"error":"expected 1 to 2 arguments, got 0","code":"invalid_arguments"}`},
+		{"no arguments", handler(none), nil, `{"success":true,"payload":"ready"}`},
+		{"unexpected argument", handler(none), []string{`1`}, `{"success":false,"error":"expected 0 arguments, got 1","code":"invalid_arguments"}`},
+		{"plain error", handler(failing), nil, `{"success":false,"error":"disk full","code":"internal"}`},
+		{"panic", handler(panicking), nil, `{"success":false,"error":"handler panicked: boom","code":"internal"}`},
+		{"required after optional", handler(bad), []string{`"a"`}, `{"success":false,"error":"required field Region follows an optional one","code":"internal"}`},
+		{"not a struct", handler(notStruct), []string{`1`}, `{"success":false,"error":"request type int is not a struct","code":"internal"}`},
+		{"zero result", handler(zero), nil, `{"success":true,"payload":0}`},
+		{"nil slice result", handler(empty), nil, `{"success":true,"payload":null}`},
+		{"nil result", handler(nothing), nil, `{"success":true,"payload":null}`},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.call(t, tc.args...); got != tc.want {
+				t.Errorf("expected %s\n\tgot      %s", tc.want, got)
+			}
+		})
+	}
+}
+
+// handler binds fn to call, for tables mixing request types.
+func handler[Req, Resp any](fn func(Req) (Resp, error)) func(t *testing.T, args ...string) string {
+	return func(t *testing.T, args ...string) string { return call(t, fn, args...) }
+}
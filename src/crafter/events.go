@@ -0,0 +1,94 @@
+package crafter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Events carries named events with JSON payloads between Go and the page.
+// Go subscribes with On, the page listens for the events Go emits as DOM
+// events on globalThis whose detail is the payload, so it may subscribe
+// before the module is loaded:
+//
+//	globalThis.addEventListener("session:completed", (e) => e.detail)
+//
+// The page emits to Go through the "emit" handler of the bridge, which
+// Crafter.emit of main.js calls.
+type Events struct {
+	mu       sync.Mutex
+	next     int
+	handlers map[string]map[int]func(json.RawMessage)
+	dispatch func(name string, detail []byte)
+}
+
+// NewEvents returns a bus handing the events emitted by Go to dispatch,
+// which is Platform.Dispatch.
+func NewEvents(dispatch func(name string, detail []byte)) *Events {
+	return &Events{handlers: map[string]map[int]func(json.RawMessage){}, dispatch: dispatch}
+}
+
+// On calls fn with the payload of every event name, until off is called.
+func (e *Events) On(name string, fn func(payload json.RawMessage)) (off func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	id := e.next
+	e.next++
+	if e.handlers[name] == nil {
+		e.handlers[name] = map[int]func(json.RawMessage){}
+	}
+	e.handlers[name][id] = fn
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.handlers[name], id)
+	}
+}
+
+// Emit delivers payload to the Go subscribers of name, then dispatches it to
+// the page. Both happen synchronously on the goroutine of the caller, before
+// Emit returns, and the listeners of the page may call the handlers of the
+// module right away: never emit while holding a lock those handlers take.
+func (e *Events) Emit(name string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", name, err)
+	}
+	e.deliver(name, data)
+	if e.dispatch != nil {
+		e.dispatch(name, data)
+	}
+	return nil
+}
+
+// deliver calls the Go subscribers of name, outside of the lock so they may
+// subscribe or emit in turn.
+func (e *Events) deliver(name string, data json.RawMessage) {
+	e.mu.Lock()
+	fns := make([]func(json.RawMessage), 0, len(e.handlers[name]))
+	for _, fn := range e.handlers[name] {
+		fns = append(fns, fn)
+	}
+	e.mu.Unlock()
+	for _, fn := range fns {
+		fn(data)
+	}
+}
+
+type emitRequest struct {
+	Name    string
+	Payload json.RawMessage `crafter:",optional"`
+}
+
+// emit serves the events emitted by the page, which already dispatched them
+// to its own listeners.
+func (e *Events) emit(req emitRequest) (string, error) {
+	if req.Name == "" {
+		return "", Errorf(CodeInvalidArgs, "missing event name")
+	}
+	if req.Payload == nil {
+		req.Payload = json.RawMessage("null")
+	}
+	e.deliver(req.Name, req.Payload)
+	return "delivered", nil
+}
@@ -0,0 +1,48 @@
+//go:build js && wasm
+
+package crafter
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+// Handle registers fn under name, the arguments of a call are decoded into
+// Req and its result or error is returned as an Envelope.
+//
+//	type submitRequest struct {
+//		CardID int
+//		Rating fsrs.Rating
+//	}
+//	crafter.Handle(wasm, "submit", m.submit)
+func Handle[Req, Resp any](w *WASM, name string, fn func(Req) (Resp, error)) {
+	w.HandleFunc(name, func(_ js.Value, args []js.Value) any {
+		return Invoke(fn, rawArgs(args)).JSValue()
+	})
+}
+
+// rawArgs encodes the arguments of a call as JSON, undefined becomes null.
+func rawArgs(args []js.Value) []json.RawMessage {
+	stringify := js.Global().Get("JSON").Get("stringify")
+	raw := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		raw[i] = json.RawMessage("null")
+		if arg.IsUndefined() {
+			continue
+		}
+		// functions and symbols stringify to undefined.
+		if s := stringify.Invoke(arg); s.Type() == js.TypeString {
+			raw[i] = json.RawMessage(s.String())
+		}
+	}
+	return raw
+}
+
+// JSValue converts the envelope into a plain JS object.
+func (e Envelope) JSValue() js.Value {
+	data, err := json.Marshal(e)
+	if err != nil {
+		data, _ = json.Marshal(Fail(Errorf(CodeInternal, "failed to encode response: %v", err)))
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
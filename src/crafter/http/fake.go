@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Fake answers the requests from Responses keyed by "METHOD URL", others get
+// a 404, and records them for checks to look at.
+type Fake struct {
+	mu        sync.Mutex
+	responses map[string]Response
+	requests  []Request
+}
+
+func NewFake() *Fake {
+	return &Fake{responses: map[string]Response{}}
+}
+
+// Handle answers method url with status and body.
+func (f *Fake) Handle(method, url string, status int, body []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[method+" "+url] = Response{Status: status, StatusText: http.StatusText(status), Header: http.Header{}, Body: body, URL: url}
+}
+
+func (f *Fake) RoundTrip(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	resp, ok := f.responses[req.Method+" "+req.URL]
+	if !ok {
+		resp = Response{Status: http.StatusNotFound, StatusText: http.StatusText(http.StatusNotFound), Header: http.Header{}, URL: req.URL}
+	}
+	resp.Body = slices.Clone(resp.Body)
+	return &resp, nil
+}
+
+// Requests returns the requests sent so far.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.requests)
+}
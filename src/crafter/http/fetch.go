@@ -0,0 +1,69 @@
+//go:build js && wasm
+
+package http
+
+import (
+	"context"
+	nethttp "net/http"
+	"syscall/js"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+func init() { defaultTransport = Fetch{} }
+
+// Fetch sends requests with the fetch API, cancelling ctx aborts them.
+type Fetch struct{}
+
+func (Fetch) RoundTrip(ctx context.Context, req Request) (*Response, error) {
+	controller := js.Global().Get("AbortController").New()
+	stop := context.AfterFunc(ctx, func() { controller.Call("abort") })
+	defer stop()
+
+	init := js.Global().Get("Object").New()
+	init.Set("method", req.Method)
+	init.Set("signal", controller.Get("signal"))
+	headers := js.Global().Get("Headers").New()
+	for key, values := range req.Header {
+		for _, value := range values {
+			headers.Call("append", key, value)
+		}
+	}
+	init.Set("headers", headers)
+	if req.Body != nil {
+		body := js.Global().Get("Uint8Array").New(len(req.Body))
+		js.CopyBytesToJS(body, req.Body)
+		init.Set("body", body)
+	}
+
+	value, err := crafter.Await(ctx, js.Global().Call("fetch", req.URL, init))
+	if err != nil {
+		return nil, err
+	}
+	resp := &Response{
+		Status:     value.Get("status").Int(),
+		StatusText: value.Get("statusText").String(),
+		Header:     readHeader(value.Get("headers")),
+		URL:        value.Get("url").String(),
+	}
+
+	buffer, err := crafter.Await(ctx, value.Call("arrayBuffer"))
+	if err != nil {
+		return nil, err
+	}
+	data := js.Global().Get("Uint8Array").New(buffer)
+	resp.Body = make([]byte, data.Length())
+	js.CopyBytesToGo(resp.Body, data)
+	return resp, nil
+}
+
+func readHeader(headers js.Value) nethttp.Header {
+	header := nethttp.Header{}
+	each := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		header.Add(args[1].String(), args[0].String())
+		return nil
+	})
+	defer each.Release()
+	headers.Call("forEach", each)
+	return header
+}
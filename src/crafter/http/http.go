@@ -0,0 +1,179 @@
+// Package http is an HTTP client over the fetch API of the page, requests
+// follow their context and the timeout of the client, and JSON endpoints are
+// read into Go values with GetJSON and PostJSON. Natively there is no fetch,
+// clients are given a Transport such as Fake.
+//
+// Like every blocking call it must run in a goroutine, such as the ones of
+// crafter.HandleAsync, never on the JS event loop.
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"time"
+)
+
+// ErrNoTransport is returned by a client without transport outside of the
+// browser.
+var ErrNoTransport = errors.New("no transport")
+
+// Transport sends a request and reads the whole response.
+type Transport interface {
+	RoundTrip(ctx context.Context, req Request) (*Response, error)
+}
+
+// defaultTransport is Fetch in the browser.
+var defaultTransport Transport
+
+// Client sends requests through its Transport.
+type Client struct {
+	// Transport defaults to Fetch in the browser.
+	Transport Transport
+	// Timeout bounds a whole request, reading the body included, zero means
+	// no limit other than the context.
+	Timeout time.Duration
+	// Header is sent with every request, the header of a request wins.
+	Header nethttp.Header
+}
+
+// DefaultClient gives up on a request after 10 seconds.
+var DefaultClient = &Client{Timeout: 10 * time.Second}
+
+type Request struct {
+	Method string
+	URL    string
+	Header nethttp.Header
+	Body   []byte
+}
+
+type Response struct {
+	Status     int
+	StatusText string
+	Header     nethttp.Header
+	Body       []byte
+	// URL is the final URL, after redirects.
+	URL string
+
+	req Request
+}
+
+// OK reports a 2xx status.
+func (r *Response) OK() bool { return r.Status >= 200 && r.Status < 300 }
+
+// Err returns a *StatusError unless the status is 2xx.
+func (r *Response) Err() error {
+	if r.OK() {
+		return nil
+	}
+	body := struct {
+		Error string `json:"error"`
+	}{}
+	_ = json.Unmarshal(r.Body, &body)
+	return &StatusError{Method: r.req.Method, URL: r.req.URL, Status: r.Status, Message: body.Error, Body: r.Body}
+}
+
+// StatusError is a status other than 2xx, as returned by Response.Err and
+// the JSON helpers.
+type StatusError struct {
+	Method string
+	URL    string
+	Status int
+	// Message is the "error" of a JSON error body, as the api answers.
+	Message string
+	Body    []byte
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s: status %d", e.Method, e.URL, e.Status)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Do sends req through the transport and reads the whole response. Any
+// status is a response, an error means no response was received or ctx
+// ended first.
+func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	if req.Method == "" {
+		req.Method = nethttp.MethodGet
+	}
+	if _, err := url.Parse(req.URL); err != nil {
+		return nil, fmt.Errorf("invalid request url: %w", err)
+	}
+	transport := c.Transport
+	if transport == nil {
+		transport = defaultTransport
+	}
+	if transport == nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrNoTransport)
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	header := nethttp.Header{}
+	for _, h := range []nethttp.Header{c.Header, req.Header} {
+		for key, values := range h {
+			header[nethttp.CanonicalHeaderKey(key)] = values
+		}
+	}
+	req.Header = header
+
+	resp, err := transport.RoundTrip(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+	}
+	resp.req = req
+	return resp, nil
+}
+
+// Get sends a GET request.
+func (c *Client) Get(ctx context.Context, url string, header nethttp.Header) (*Response, error) {
+	return c.Do(ctx, Request{Method: nethttp.MethodGet, URL: url, Header: header})
+}
+
+// GetJSON reads the JSON answer of url into a T.
+func GetJSON[T any](ctx context.Context, c *Client, url string) (T, error) {
+	return doJSON[T](ctx, c, Request{Method: nethttp.MethodGet, URL: url})
+}
+
+// PostJSON sends body as JSON to url and reads the JSON answer into a T.
+func PostJSON[T any](ctx context.Context, c *Client, url string, body any) (T, error) {
+	var zero T
+	data, err := json.Marshal(body)
+	if err != nil {
+		return zero, fmt.Errorf("failed to encode request body: %w", err)
+	}
+	header := nethttp.Header{}
+	header.Set("Content-Type", "application/json")
+	return doJSON[T](ctx, c, Request{Method: nethttp.MethodPost, URL: url, Header: header, Body: data})
+}
+
+func doJSON[T any](ctx context.Context, c *Client, req Request) (T, error) {
+	var v T
+	if req.Header == nil {
+		req.Header = nethttp.Header{}
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return v, err
+	}
+	if err := resp.Err(); err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(resp.Body, &v); err != nil {
+		return v, fmt.Errorf("%s %s: failed to decode response: %w", req.Method, req.URL, err)
+	}
+	return v, nil
+}
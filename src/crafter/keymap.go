@@ -0,0 +1,132 @@
+package crafter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Shortcut binds a key of the keyboard to an action of the page. main.js
+// calls Handler with the values of Input, in the craft-input syntax, then
+// Args, and emits the "shortcut" event {action, result} once it succeeded.
+// A shortcut without handler only emits the event.
+type Shortcut struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	// Scope is the route pattern the shortcut applies on, every page when
+	// empty.
+	Scope   string `json:"scope,omitempty"`
+	Handler string `json:"handler,omitempty"`
+	Input   string `json:"input,omitempty"`
+	Args    []any  `json:"args,omitempty"`
+}
+
+// conflicts reports whether both shortcuts would apply to the same key
+// press.
+func (s Shortcut) conflicts(other Shortcut) bool {
+	return s.Action != other.Action && s.Key == other.Key &&
+		(s.Scope == "" || other.Scope == "" || s.Scope == other.Scope)
+}
+
+// NormalizeKey returns key as main.js sends it: lower case, " " is "space".
+func NormalizeKey(key string) string {
+	if key == " " {
+		return "space"
+	}
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+// shortcutsKey holds the keys the user chose, by action.
+const shortcutsKey = "shortcuts"
+
+// Keymap holds the shortcuts of the module, the keys chosen in the settings
+// are kept in the storage. Bind it to the "shortcuts" key of the store for
+// main.js to follow:
+//
+//	crafter.Bind(store, "shortcuts", keymap)
+type Keymap struct {
+	storage   Storage
+	shortcuts *Signal[[]Shortcut]
+}
+
+// NewKeymap returns the defaults with the keys saved in storage, it panics
+// when two defaults conflict. Saved keys conflicting with each other or the
+// defaults are all dropped.
+func NewKeymap(storage Storage, defaults ...Shortcut) *Keymap {
+	shortcuts := make([]Shortcut, len(defaults))
+	for i, s := range defaults {
+		s.Key = NormalizeKey(s.Key)
+		shortcuts[i] = s
+	}
+	if err := checkShortcuts(shortcuts); err != nil {
+		panic("crafter: " + err.Error())
+	}
+
+	saved := map[string]string{}
+	if err := StorageGetItem(storage, shortcutsKey, &saved); err == nil {
+		chosen := slices.Clone(shortcuts)
+		for i, s := range chosen {
+			if key := NormalizeKey(saved[s.Action]); key != "" {
+				chosen[i].Key = key
+			}
+		}
+		if err := checkShortcuts(chosen); err != nil {
+			fmt.Println("crafter: dropping the saved shortcuts:", err)
+		} else {
+			shortcuts = chosen
+		}
+	}
+	return &Keymap{storage: storage, shortcuts: NewSignalFunc(shortcuts, nil)}
+}
+
+func checkShortcuts(shortcuts []Shortcut) error {
+	for i, s := range shortcuts {
+		if j := slices.IndexFunc(shortcuts[:i], s.conflicts); j >= 0 {
+			return fmt.Errorf("shortcuts %s and %s both use %q", shortcuts[j].Action, s.Action, s.Key)
+		}
+	}
+	return nil
+}
+
+func (k *Keymap) Get() []Shortcut { return slices.Clone(k.shortcuts.Get()) }
+
+func (k *Keymap) Subscribe(fn func([]Shortcut)) (off func()) { return k.shortcuts.Subscribe(fn) }
+
+// Shortcuts lists the shortcuts, for the settings.
+func (k *Keymap) Shortcuts(struct{}) ([]Shortcut, error) {
+	return k.Get(), nil
+}
+
+type RebindRequest struct {
+	Action string
+	Key    string
+}
+
+// Rebind moves the shortcut of an action to another key and saves it, the
+// key may not be in use on the pages of the action.
+func (k *Keymap) Rebind(req RebindRequest) (string, error) {
+	shortcuts := k.Get()
+	i := slices.IndexFunc(shortcuts, func(s Shortcut) bool { return s.Action == req.Action })
+	if i < 0 {
+		return "", Errorf(CodeNotFound, "no shortcut %s", req.Action)
+	}
+	s := shortcuts[i]
+	s.Key = NormalizeKey(req.Key)
+	if s.Key == "" {
+		return "", Errorf(CodeInvalidArgs, "missing key for %s", req.Action)
+	}
+	if j := slices.IndexFunc(shortcuts, s.conflicts); j >= 0 {
+		return "", Errorf(CodeInvalidArgs, "key %q is used by %s", s.Key, shortcuts[j].Action)
+	}
+	shortcuts[i] = s
+
+	saved := make(map[string]string, len(shortcuts))
+	for _, s := range shortcuts {
+		saved[s.Action] = s.Key
+	}
+	if err := StorageSetItem(k.storage, shortcutsKey, saved); err != nil {
+		return "", err
+	}
+	k.shortcuts.Set(shortcuts)
+	return "saved", nil
+}
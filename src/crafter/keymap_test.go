@@ -0,0 +1,76 @@
+package crafter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+var defaultShortcuts = []crafter.Shortcut{
+	{Action: "reveal", Key: " ", Scope: "/session"},
+	{Action: "rate", Key: "1", Scope: "/session", Handler: "submit"},
+	{Action: "help", Key: "H"},
+	{Action: "stats", Key: "s", Scope: "/stats"},
+}
+
+func keys(k *crafter.Keymap) string {
+	keys := []string{}
+	for _, s := range k.Get() {
+		keys = append(keys, s.Key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// TestRebind rebinds shortcuts around the conflicts, in order.
+func TestRebind(t *testing.T) {
+	page := crafter.NewFake("/")
+	keymap := crafter.NewKeymap(page.Storage(), defaultShortcuts...)
+	tcs := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"rebind", []string{`"rate"`, `"R"`}, `{"success":true,"payload":"saved"}`},
+		{"same key", []string{`"rate"`, `"r"`}, `{"success":true,"payload":"saved"}`},
+		{"other page", []string{`"stats"`, `"space"`}, `{"success":true,"payload":"saved"}`},
+		{"conflict", []string{`"reveal"`, `"r"`}, `{"success":false,"error":"key \"r\" is used by rate","code":"invalid_arguments"}`},
+		{"every page", []string{`"help"`, `"space"`}, `{"success":false,"error":"key \"space\" is used by reveal","code":"invalid_arguments"}`},
+		{"unknown", []string{`"fly"`, `"f"`}, `{"success":false,"error":"no shortcut fly","code":"not_found"}`},
+		{"space", []string{`"rate"`, `" "`}, `{"success":false,"error":"key \"space\" is used by reveal","code":"invalid_arguments"}`},
+		{"nothing", []string{`"rate"`, `""`}, `{"success":false,"error":"missing key for rate","code":"invalid_arguments"}`},
+	}
+	for _, tc := range tcs {
+		if got := call(t, keymap.Rebind, tc.args...); got != tc.want {
+			t.Errorf("%s: expected %s\n\tgot      %s", tc.name, tc.want, got)
+		}
+	}
+	if got := keys(keymap); got != "space,r,h,space" {
+		t.Errorf("expected keys space,r,h,space, got %s", got)
+	}
+}
+
+func TestNewKeymap(t *testing.T) {
+	page := crafter.NewFake("/")
+	_ = page.Storage().SetItem("shortcuts", `{"rate":"r","stats":"space"}`)
+	if got := keys(crafter.NewKeymap(page.Storage(), defaultShortcuts...)); got != "space,r,h,space" {
+		t.Errorf("saved keys: got %s", got)
+	}
+	// two actions may swap their keys.
+	_ = page.Storage().SetItem("shortcuts", `{"reveal":"1","rate":"space"}`)
+	if got := keys(crafter.NewKeymap(page.Storage(), defaultShortcuts...)); got != "1,space,h,s" {
+		t.Errorf("swapped keys: got %s", got)
+	}
+	// the defaults win over the saved keys taking them.
+	_ = page.Storage().SetItem("shortcuts", `{"reveal":"h"}`)
+	if got := keys(crafter.NewKeymap(page.Storage(), defaultShortcuts...)); got != "space,1,h,s" {
+		t.Errorf("conflicting saved keys: got %s", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("conflicting defaults: expected a panic")
+		}
+	}()
+	crafter.NewKeymap(page.Storage(), defaultShortcuts[0], crafter.Shortcut{Action: "other", Key: "space"})
+}
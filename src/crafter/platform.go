@@ -0,0 +1,192 @@
+package crafter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// Platform is the page the handlers run in. Browser is the real one, Fake
+// keeps everything in memory so handlers run natively, without syscall/js.
+type Platform interface {
+	// Storage is the localStorage of the page.
+	Storage() Storage
+	// Location is the address of the page.
+	Location() Location
+	// Dispatch fires a DOM event on the global object, detail is the JSON
+	// of its payload.
+	Dispatch(name string, detail []byte)
+}
+
+// Storage holds strings by key and survives reloads.
+type Storage interface {
+	// GetItem reports false when key has no item.
+	GetItem(key string) (string, bool)
+	SetItem(key, value string) error
+	RemoveItem(key string) error
+}
+
+type Location interface {
+	// Href is the whole URL of the page.
+	Href() string
+	// Assign navigates to url.
+	Assign(url string)
+	Reload()
+	// Push and Replace move to url through the History API, without
+	// loading it.
+	Push(url string)
+	Replace(url string)
+}
+
+// ErrNoItem is returned by StorageGetItem for a key without item.
+var ErrNoItem = errors.New("no item in storage")
+
+// StorageSetItem stores data as JSON under key.
+func StorageSetItem(s Storage, key string, data any) error {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to push state to storage: %w", err)
+	}
+	return s.SetItem(key, string(dataBytes))
+}
+
+// StorageGetItem decodes the JSON stored under key into to.
+func StorageGetItem(s Storage, key string, to any) error {
+	data, ok := s.GetItem(key)
+	if !ok || data == "" {
+		return fmt.Errorf("%w: %s", ErrNoItem, key)
+	}
+	if err := json.Unmarshal([]byte(data), to); err != nil {
+		return errors.New("could not deserialize the data, got: " + err.Error())
+	}
+	return nil
+}
+
+// Fake is a Platform in memory, it records the navigations and the events
+// of the handlers for checks to look at.
+type Fake struct {
+	mu      sync.Mutex
+	items   map[string]string
+	href    string
+	visits  []string
+	history []string
+	events  []FakeEvent
+}
+
+type FakeEvent struct {
+	Name   string
+	Detail json.RawMessage
+}
+
+// NewFake returns an empty page at href.
+func NewFake(href string) *Fake {
+	return &Fake{items: map[string]string{}, href: href}
+}
+
+func (f *Fake) Storage() Storage   { return fakeStorage{f} }
+func (f *Fake) Location() Location { return fakeLocation{f} }
+
+func (f *Fake) Dispatch(name string, detail []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, FakeEvent{Name: name, Detail: slices.Clone(detail)})
+}
+
+// Events returns the events dispatched so far.
+func (f *Fake) Events() []FakeEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.events)
+}
+
+// Visits returns the URLs assigned and reloaded so far.
+func (f *Fake) Visits() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.visits)
+}
+
+// History returns the entries of the history, the current one last.
+func (f *Fake) History() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.history)
+}
+
+type fakeStorage struct{ f *Fake }
+
+func (s fakeStorage) GetItem(key string) (string, bool) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	value, ok := s.f.items[key]
+	return value, ok
+}
+
+func (s fakeStorage) SetItem(key, value string) error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	s.f.items[key] = value
+	return nil
+}
+
+func (s fakeStorage) RemoveItem(key string) error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	delete(s.f.items, key)
+	return nil
+}
+
+type fakeLocation struct{ f *Fake }
+
+func (l fakeLocation) Href() string {
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	return l.f.href
+}
+
+func (l fakeLocation) Assign(url string) {
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	l.f.href = url
+	l.f.visits = append(l.f.visits, url)
+}
+
+func (l fakeLocation) Reload() {
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	l.f.visits = append(l.f.visits, l.f.href)
+}
+
+func (l fakeLocation) Push(url string) {
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	l.f.href = l.f.resolve(url)
+	l.f.history = append(l.f.history, l.f.href)
+}
+
+func (l fakeLocation) Replace(url string) {
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	l.f.href = l.f.resolve(url)
+	if len(l.f.history) == 0 {
+		l.f.history = append(l.f.history, l.f.href)
+		return
+	}
+	l.f.history[len(l.f.history)-1] = l.f.href
+}
+
+// resolve returns ref relative to the current page, like the browser does.
+func (f *Fake) resolve(ref string) string {
+	base, err := url.Parse(f.href)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
@@ -0,0 +1,110 @@
+// Package render turns components into HTML with html/template sets parsed
+// once, from the templates a module embeds.
+//
+// A set reads three directories of a file system:
+//
+//	layouts/*.html   shared wrappers, filled through {{block "content" .}}
+//	partials/*.html  shared {{define "name"}} snippets
+//	views/*.html     one template per component, named after its file
+//
+// Each view is parsed on its own copy of the layouts and partials, so every
+// view may define its "content" for the layout it renders in:
+//
+//	{{template "page" .}}
+//	{{define "content"}}{{range .Records}}{{template "record" .}}{{end}}{{end}}
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Component is the data of a view. Models return components, the views
+// render them.
+type Component interface {
+	// View names the template in views/ rendering the component.
+	View() string
+}
+
+// ErrNoView is returned when a component names a view the set lacks.
+var ErrNoView = errors.New("no such view")
+
+// Set holds the views parsed from a file system.
+type Set struct {
+	views map[string]*template.Template
+}
+
+// Parse parses the layouts, partials and views of fsys, funcs are available
+// to all of them.
+func Parse(fsys fs.FS, funcs template.FuncMap) (*Set, error) {
+	base := template.New("").Funcs(funcs)
+	for _, dir := range []string{"layouts", "partials"} {
+		matches, err := fs.Glob(fsys, dir+"/*.html")
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if base, err = base.ParseFS(fsys, matches...); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", dir, err)
+		}
+	}
+
+	views, err := fs.Glob(fsys, "views/*.html")
+	if err != nil {
+		return nil, err
+	}
+	set := &Set{views: make(map[string]*template.Template, len(views))}
+	for _, file := range views {
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if set.views[name], err = tmpl.New(name).Parse(string(data)); err != nil {
+			return nil, fmt.Errorf("failed to parse view %s: %w", name, err)
+		}
+	}
+	return set, nil
+}
+
+// MustParse is Parse for the templates embedded in a module, which are known
+// to be valid once it runs.
+func MustParse(fsys fs.FS, funcs template.FuncMap) *Set {
+	set, err := Parse(fsys, funcs)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Render writes the view of c to w.
+func (s *Set) Render(w io.Writer, c Component) error {
+	tmpl, ok := s.views[c.View()]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoView, c.View())
+	}
+	if err := tmpl.Execute(w, c); err != nil {
+		return fmt.Errorf("failed to render %s: %w", c.View(), err)
+	}
+	return nil
+}
+
+// HTML renders c to a string, for handlers returning markup to the page.
+func (s *Set) HTML(c Component) (template.HTML, error) {
+	buf := &strings.Builder{}
+	if err := s.Render(buf, c); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,52 @@
+package render_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hnimtadd/spaced/src/crafter/render"
+)
+
+type listView struct {
+	Title string
+	Items []string
+}
+
+func (listView) View() string { return "list" }
+
+type emptyView struct{}
+
+func (emptyView) View() string { return "empty" }
+
+type missingView struct{}
+
+func (missingView) View() string { return "missing" }
+
+func TestRender(t *testing.T) {
+	set, err := render.Parse(fstest.MapFS{
+		"layouts/page.html":  {Data: []byte(`{{define "page"}}<main>{{block "content" .}}{{end}}</main>{{end}}`)},
+		"partials/item.html": {Data: []byte(`{{define "item"}}<li>{{.}}</li>{{end}}`)},
+		"views/list.html":    {Data: []byte(`{{template "page" .}}{{define "content"}}<h1>{{.Title}}</h1>{{range .Items}}{{template "item" .}}{{end}}{{end}}`)},
+		"views/empty.html":   {Data: []byte(`{{template "page" .}}`)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := set.HTML(listView{Title: "a < b", Items: []string{"x", "y"}})
+	if want := `<main><h1>a &lt; b</h1><li>x</li><li>y</li></main>`; err != nil || string(html) != want {
+		t.Errorf("layout and partials: expected %q, got %q %v", want, html, err)
+	}
+	// the content defined by list is not shared with empty.
+	if html, err := set.HTML(emptyView{}); err != nil || html != `<main></main>` {
+		t.Errorf("content of another view: got %q %v", html, err)
+	}
+	if _, err := set.HTML(missingView{}); !errors.Is(err, render.ErrNoView) {
+		t.Errorf("unknown view: expected ErrNoView, got %v", err)
+	}
+	if _, err := render.Parse(fstest.MapFS{"views/bad.html": {Data: []byte(`{{if}}`)}}, nil); err == nil || !strings.Contains(err.Error(), "view bad") {
+		t.Errorf("invalid view: got %v", err)
+	}
+}
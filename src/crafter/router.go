@@ -0,0 +1,159 @@
+package crafter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Route is the match of a path against the pattern of a view.
+type Route struct {
+	// Pattern is the pattern the view was registered with.
+	Pattern string `json:"pattern"`
+	// Path is the path of the page with its query, as it is pushed to the
+	// history.
+	Path   string            `json:"path"`
+	Params map[string]string `json:"params"`
+	Query  url.Values        `json:"-"`
+}
+
+// ViewFunc renders the page of a route to the HTML mounted in the outlet of
+// the page.
+type ViewFunc func(ctx context.Context, route Route) (string, error)
+
+// Router maps the paths of a single page to the views rendering them and
+// keeps the history of the page in step. Patterns are made of segments, a
+// ":name" segment matches any segment and is passed as Params["name"]:
+//
+//	r.Handle("/decks/:id", deckView)
+//
+// The page navigates through the "navigate" handler, Crafter.navigate of
+// main.js mounts the page it returns.
+type Router struct {
+	platform Platform
+
+	mu       sync.Mutex
+	routes   []route
+	notFound ViewFunc
+}
+
+type route struct {
+	pattern  string
+	segments []string
+	view     ViewFunc
+}
+
+func NewRouter(platform Platform) *Router {
+	return &Router{platform: platform}
+}
+
+// Handle registers view for the paths matching pattern, the first pattern
+// registered wins when several match.
+func (r *Router) Handle(pattern string, view ViewFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{pattern: pattern, segments: segments(pattern), view: view})
+}
+
+// NotFound registers the view of the paths without route loaded as the page
+// itself, the SPA fallback of the server serves the module for them.
+func (r *Router) NotFound(view ViewFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notFound = view
+}
+
+func segments(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// Match returns the route and the view of path, which may be relative to the
+// current page and carry a query.
+func (r *Router) Match(path string) (Route, ViewFunc, bool) {
+	base, err := url.Parse(r.platform.Location().Href())
+	if err != nil {
+		base = &url.URL{Path: "/"}
+	}
+	u, err := base.Parse(path)
+	if err != nil || u.Host != base.Host {
+		return Route{}, nil, false
+	}
+	requested := segments(u.Path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rt := range r.routes {
+		if len(rt.segments) != len(requested) {
+			continue
+		}
+		params := map[string]string{}
+		for i, segment := range rt.segments {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				params[name] = requested[i]
+				continue
+			}
+			if segment != requested[i] {
+				params = nil
+				break
+			}
+		}
+		if params == nil {
+			continue
+		}
+		route := Route{Pattern: rt.pattern, Path: u.RequestURI(), Params: params, Query: u.Query()}
+		return route, rt.view, true
+	}
+	return Route{}, nil, false
+}
+
+type NavigateRequest struct {
+	Path string
+	// Replace replaces the current entry of the history instead of pushing
+	// one, for the first page and the back and forward buttons.
+	Replace bool `crafter:",optional"`
+}
+
+// Page is a rendered route, to be mounted in the outlet.
+type Page struct {
+	Route
+	HTML string `json:"html"`
+}
+
+// Navigate renders the page of req.Path, then moves the history to it. The
+// page loads the paths without route on not_found, they are not ours, but
+// the NotFound view renders them when they replace the page.
+func (r *Router) Navigate(ctx context.Context, req NavigateRequest) (Page, error) {
+	route, view, ok := r.Match(req.Path)
+	if !ok {
+		r.mu.Lock()
+		view = r.notFound
+		r.mu.Unlock()
+		if !req.Replace || view == nil {
+			return Page{}, Errorf(CodeNotFound, "no route for %s", req.Path)
+		}
+		route = Route{Path: req.Path, Params: map[string]string{}}
+	}
+	html, err := view(ctx, route)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to render %s: %w", route.Path, err)
+	}
+	// a later navigation took over while rendering.
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+	location := r.platform.Location()
+	current, _ := url.Parse(location.Href())
+	// the page again is not a new entry of the history.
+	if req.Replace || current != nil && current.RequestURI() == route.Path {
+		location.Replace(route.Path)
+	} else {
+		location.Push(route.Path)
+	}
+	return Page{Route: route, HTML: html}, nil
+}
@@ -0,0 +1,187 @@
+package crafter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Observable is a value which changes over time, Signal and Computed are.
+type Observable[T any] interface {
+	Get() T
+	// Subscribe calls fn with every new value, until off is called.
+	Subscribe(fn func(T)) (off func())
+}
+
+// Dependency is a value computed values follow, Signal and Computed are.
+type Dependency interface {
+	observe(fn func()) (off func())
+}
+
+// Signal holds a value and notifies its subscribers when it is set to
+// another one.
+type Signal[T any] struct {
+	mu    sync.Mutex
+	value T
+	equal func(a, b T) bool
+	next  int
+	subs  map[int]func(T)
+}
+
+// NewSignal returns a signal of a comparable value, setting a value == to the
+// current one notifies nobody. A pointer set again after its target changed
+// is such a value, use NewSignalFunc for those.
+func NewSignal[T comparable](value T) *Signal[T] {
+	return NewSignalFunc(value, func(a, b T) bool { return a == b })
+}
+
+// NewSignalFunc returns a signal which skips the values equal reports equal
+// to the current one, a nil equal notifies on every Set.
+func NewSignalFunc[T any](value T, equal func(a, b T) bool) *Signal[T] {
+	return &Signal[T]{value: value, equal: equal, subs: map[int]func(T){}}
+}
+
+func (s *Signal[T]) Get() T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value
+}
+
+// Set replaces the value, a value equal to the current one notifies nobody.
+func (s *Signal[T]) Set(value T) {
+	s.mu.Lock()
+	if s.equal != nil && s.equal(s.value, value) {
+		s.mu.Unlock()
+		return
+	}
+	s.value = value
+	fns := make([]func(T), 0, len(s.subs))
+	for _, fn := range s.subs {
+		fns = append(fns, fn)
+	}
+	s.mu.Unlock()
+	// outside of the lock, so subscribers may read or set in turn.
+	for _, fn := range fns {
+		fn(value)
+	}
+}
+
+// Update sets the value fn returns for the current one.
+func (s *Signal[T]) Update(fn func(T) T) {
+	s.Set(fn(s.Get()))
+}
+
+func (s *Signal[T]) Subscribe(fn func(T)) (off func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.next
+	s.next++
+	s.subs[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+func (s *Signal[T]) observe(fn func()) (off func()) {
+	return s.Subscribe(func(T) { fn() })
+}
+
+// Computed is a value derived from others, it is computed again whenever one
+// of them changes until Stop is called.
+type Computed[T any] struct {
+	signal *Signal[T]
+	fn     func() T
+	offs   []func()
+}
+
+// NewComputed returns the value of fn, which reads deps. A new value == to
+// the current one notifies nobody.
+func NewComputed[T comparable](fn func() T, deps ...Dependency) *Computed[T] {
+	return NewComputedFunc(fn, func(a, b T) bool { return a == b }, deps...)
+}
+
+// NewComputedFunc is NewComputed for values compared with equal, see
+// NewSignalFunc.
+func NewComputedFunc[T any](fn func() T, equal func(a, b T) bool, deps ...Dependency) *Computed[T] {
+	c := &Computed[T]{signal: NewSignalFunc(fn(), equal), fn: fn}
+	for _, dep := range deps {
+		c.offs = append(c.offs, dep.observe(func() { c.signal.Set(c.fn()) }))
+	}
+	return c
+}
+
+// Stop unsubscribes from the dependencies, the value is no longer computed
+// and its subscribers are not notified anymore.
+func (c *Computed[T]) Stop() {
+	for _, off := range c.offs {
+		off()
+	}
+	c.offs = nil
+}
+
+func (c *Computed[T]) Get() T { return c.signal.Get() }
+
+func (c *Computed[T]) Subscribe(fn func(T)) (off func()) { return c.signal.Subscribe(fn) }
+
+func (c *Computed[T]) observe(fn func()) (off func()) { return c.signal.observe(fn) }
+
+// Store publishes observable values to the elements of the page bound to
+// them by key, main.js keeps them up to date:
+//
+//	<span craft-bind="remaining"></span>
+//	<h3 craft-bind="card.word"></h3>
+//	<div craft-bind="card.ID:[data-card]"></div>
+//
+// Keys have no dots, what follows the key is a path into the value and
+// ":prop" sets the innerHTML or an [attribute] instead of the text.
+type Store struct {
+	events *Events
+
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+}
+
+func NewStore(events *Events) *Store {
+	return &Store{events: events, values: map[string]json.RawMessage{}}
+}
+
+// storeChanged is emitted to the page for every new value of a key.
+type storeChanged struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Bind publishes the value of o under key now and whenever it changes,
+// until off is called.
+func Bind[T any](s *Store, key string, o Observable[T]) (off func()) {
+	if strings.Contains(key, ".") {
+		panic(fmt.Sprintf("crafter: store key %q has a dot", key))
+	}
+	s.publish(key, o.Get())
+	return o.Subscribe(func(value T) { s.publish(key, value) })
+}
+
+func (s *Store) publish(key string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		fmt.Printf("crafter: failed to encode %s: %v\n", key, err)
+		return
+	}
+	s.mu.Lock()
+	s.values[key] = data
+	s.mu.Unlock()
+	if err := s.events.Emit("store:changed", storeChanged{Key: key, Value: data}); err != nil {
+		fmt.Printf("crafter: failed to publish %s: %v\n", key, err)
+	}
+}
+
+// Value returns the JSON of the last value published under key.
+func (s *Store) Value(key string) (json.RawMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
@@ -0,0 +1,79 @@
+package crafter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+// TestStore follows signals through computed values to the events of the
+// store.
+func TestStore(t *testing.T) {
+	page := crafter.NewFake("/")
+	store := crafter.NewStore(crafter.NewEvents(page.Dispatch))
+	count := crafter.NewSignal(1)
+	label := crafter.NewSignal("cards")
+	title := crafter.NewComputed(func() string {
+		return fmt.Sprintf("%d %s", count.Get(), label.Get())
+	}, count, label)
+
+	changes := 0
+	off := title.Subscribe(func(string) { changes++ })
+	crafter.Bind(store, "title", title)
+	if initial, _ := store.Value("title"); string(initial) != `"1 cards"` {
+		t.Errorf("bound value: expected the value when bound, got %s", initial)
+	}
+	count.Set(2)
+	count.Set(2)
+	label.Update(strings.ToUpper)
+	off()
+	count.Set(3)
+
+	if value, _ := store.Value("title"); title.Get() != "3 CARDS" || string(value) != `"3 CARDS"` {
+		t.Errorf("computed: expected 3 CARDS, got %q %s", title.Get(), value)
+	}
+	if changes != 2 {
+		t.Errorf("equal value: expected 2 changes, got %d", changes)
+	}
+	events := page.Events()
+	if last := events[len(events)-1]; len(events) != 4 || last.Name != "store:changed" || string(last.Detail) != `{"key":"title","value":"3 CARDS"}` {
+		t.Errorf("store changed: got %v", events)
+	}
+}
+
+// TestSignalEqual sets a pointer again after its target changed.
+func TestSignalEqual(t *testing.T) {
+	type card struct{ word string }
+	c := &card{word: "tenet"}
+	for _, tt := range []struct {
+		name   string
+		signal *crafter.Signal[*card]
+		want   int
+	}{
+		{"==", crafter.NewSignal(c), 0},
+		{"nil equal", crafter.NewSignalFunc(c, nil), 1},
+		{"by word", crafter.NewSignalFunc(c, func(a, b *card) bool { return a.word == b.word }), 0},
+	} {
+		changes := 0
+		tt.signal.Subscribe(func(*card) { changes++ })
+		tt.signal.Set(c)
+		if changes != tt.want {
+			t.Errorf("%s: expected %d changes, got %d", tt.name, tt.want, changes)
+		}
+	}
+}
+
+func TestComputedStop(t *testing.T) {
+	count := crafter.NewSignal(1)
+	double := crafter.NewComputed(func() int { return 2 * count.Get() }, count)
+	changes := 0
+	double.Subscribe(func(int) { changes++ })
+	count.Set(2)
+	double.Stop()
+	count.Set(3)
+	if double.Get() != 4 || changes != 1 {
+		t.Errorf("stopped: expected 4 after 1 change, got %d after %d", double.Get(), changes)
+	}
+}
@@ -0,0 +1,40 @@
+// page stands in for the browser globals the wasm modules use but node
+// lacks, TestMain of browser_test.go evaluates it before the tests.
+"use strict";
+
+const items = new Map();
+globalThis.localStorage = {
+  getItem: (key) => (items.has(key) ? items.get(key) : null),
+  setItem: (key, value) => items.set(key, String(value)),
+  removeItem: (key) => items.delete(key),
+  clear: () => items.clear(),
+};
+
+globalThis.visits = [];
+globalThis.location = {
+  href: "https://spaced.test/session?id=3",
+  assign(url) {
+    this.href = url;
+    globalThis.visits.push(url);
+  },
+  reload() {
+    globalThis.visits.push(this.href);
+  },
+};
+
+globalThis.history = {
+  entries: [globalThis.location.href],
+  pushState(_state, _title, url) {
+    this.entries.push(url);
+    globalThis.location.href = url;
+  },
+  replaceState(_state, _title, url) {
+    this.entries[this.entries.length - 1] = url;
+    globalThis.location.href = url;
+  },
+};
+
+const target = new EventTarget();
+globalThis.addEventListener = target.addEventListener.bind(target);
+globalThis.removeEventListener = target.removeEventListener.bind(target);
+globalThis.dispatchEvent = target.dispatchEvent.bind(target);
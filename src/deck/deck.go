@@ -0,0 +1,144 @@
+package deck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hnimtadd/spaced/src/core/fsrs"
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/dictionary"
+)
+
+var ErrExists = errors.New("card already in deck")
+
+// Suggest looks word up in the dictionary and returns one card per sense.
+func Suggest(ctx context.Context, word, region string) ([]model.Card, error) {
+	entry, err := dictionary.Lookup(ctx, word)
+	if err != nil {
+		return nil, err
+	}
+	return Candidates(entry, region), nil
+}
+
+// Candidates turns every sense of entry into a card, the IPA is taken from
+// region when the part of speech has it, else from the first pronunciation.
+func Candidates(entry *dictionary.Entry, region string) []model.Card {
+	cards := []model.Card{}
+	for _, block := range entry.Blocks {
+		ipa := ""
+		for _, pron := range block.Pronunciations {
+			if ipa == "" || pron.Region == region {
+				ipa = pron.IPA
+			}
+			if pron.Region == region {
+				break
+			}
+		}
+		for _, sense := range block.Senses {
+			if sense.Definition == "" {
+				continue
+			}
+			card := model.Card{
+				Word:       entry.Headword,
+				IPA:        ipa,
+				Definition: sense.Definition,
+			}
+			if len(sense.Examples) > 0 {
+				card.Example = sense.Examples[0]
+			}
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// Deck is a card file on disk. Existing entries are kept verbatim, so the
+// progress they carry survives adding new cards.
+type Deck struct {
+	entries []json.RawMessage
+	words   map[string]bool
+}
+
+// Load reads the deck at path, a missing file is an empty deck.
+func Load(path string) (*Deck, error) {
+	d := &Deck{
+		entries: []json.RawMessage{},
+		words:   map[string]bool{},
+	}
+	fileBytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deck: %w", err)
+	}
+	if err := json.Unmarshal(fileBytes, &d.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse deck: %w", err)
+	}
+	for _, entry := range d.entries {
+		card := model.Card{}
+		if err := json.Unmarshal(entry, &card); err != nil {
+			return nil, fmt.Errorf("failed to parse card: %w", err)
+		}
+		d.words[fsrs.Key(card.Word)] = true
+	}
+	return d, nil
+}
+
+func (d *Deck) Len() int { return len(d.entries) }
+
+func (d *Deck) Has(word string) bool { return d.words[fsrs.Key(word)] }
+
+// Add appends a new card with the content of card to the deck, the
+// scheduling fields start over. It returns ErrExists if the word is already
+// there.
+func (d *Deck) Add(card model.Card) error {
+	if d.Has(card.Word) {
+		return ErrExists
+	}
+	card = model.Card{
+		ID:         len(d.entries),
+		Word:       card.Word,
+		IPA:        card.IPA,
+		Definition: card.Definition,
+		Example:    card.Example,
+	}
+	entry, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	d.entries = append(d.entries, entry)
+	d.words[fsrs.Key(card.Word)] = true
+	return nil
+}
+
+// Save writes the deck to path through a temporary file, so a failed write
+// never leaves a truncated deck behind.
+func (d *Deck) Save(path string) error {
+	content, err := json.Marshal(d.entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".deck-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp deck: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write deck: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write deck: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write deck: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
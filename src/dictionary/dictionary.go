@@ -0,0 +1,226 @@
+package dictionary
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	coreHtml "github.com/hnimtadd/spaced/src/html"
+	"github.com/hnimtadd/spaced/src/upstream"
+	"golang.org/x/net/html"
+)
+
+// BaseURL is the dictionary upstream, it is a variable so tests can point
+// it to a local server.
+var BaseURL = "https://dictionary.cambridge.org"
+
+var (
+	ErrNotFound    = errors.New("word not found in dictionary")
+	ErrNoSound     = errors.New("could not find any sound url")
+	ErrInvalidWord = errors.New("invalid word")
+)
+
+// MaxWordLength is the longest word, in runes, Lookup accepts.
+const MaxWordLength = 64
+
+// ValidateWord accepts headwords made of letters, combining marks,
+// apostrophes, hyphens, dots and single spaces, such as "o'clock",
+// "well-being", "a.m." or "ice cream". It must start with a letter, so it
+// can never climb up the dictionary path.
+func ValidateWord(word string) error {
+	if word == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidWord)
+	}
+	if utf8.RuneCountInString(word) > MaxWordLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidWord, MaxWordLength)
+	}
+	prev := ' '
+	for i, r := range word {
+		switch {
+		case unicode.IsLetter(r):
+		case i == 0:
+			return fmt.Errorf("%w: must start with a letter", ErrInvalidWord)
+		case unicode.Is(unicode.M, r), r == '\'', r == '’', r == '-', r == '.':
+		case r == ' ' && prev != ' ':
+		default:
+			return fmt.Errorf("%w: unexpected %q", ErrInvalidWord, r)
+		}
+		prev = r
+	}
+	return nil
+}
+
+// Entry is the structured content of a dictionary page.
+type Entry struct {
+	Headword string  `json:"headword"`
+	Blocks   []Block `json:"blocks"`
+}
+
+// Block groups the pronunciations and senses of one part of speech.
+type Block struct {
+	POS            string          `json:"pos"`
+	Pronunciations []Pronunciation `json:"pronunciations"`
+	Senses         []Sense         `json:"senses"`
+}
+
+type Pronunciation struct {
+	Region   string `json:"region"`
+	IPA      string `json:"ipa"`
+	AudioURL string `json:"audioURL"`
+}
+
+type Sense struct {
+	Definition string   `json:"definition"`
+	Examples   []string `json:"examples"`
+}
+
+// SoundURL returns the audio of the pronunciation matching ipa in region.
+// Some words have several IPA flavors, so another pronunciation of the same
+// region is used when none matches exactly.
+func (e *Entry) SoundURL(ipa, region string) (string, error) {
+	candidate := ""
+	for _, block := range e.Blocks {
+		for _, pron := range block.Pronunciations {
+			if pron.Region != region || pron.AudioURL == "" {
+				continue
+			}
+			if normalizeIPA(pron.IPA) == normalizeIPA(ipa) {
+				return pron.AudioURL, nil
+			}
+			if candidate == "" {
+				candidate = pron.AudioURL
+			}
+		}
+	}
+	if candidate == "" {
+		return "", ErrNoSound
+	}
+	return candidate, nil
+}
+
+// normalizeIPA drops the syllable dots and slashes the dictionary adds
+// around transcriptions.
+func normalizeIPA(ipa string) string {
+	return strings.NewReplacer(".", "", "/", "", " ", "").Replace(ipa)
+}
+
+// Lookup downloads the dictionary page of word and parses it.
+func Lookup(ctx context.Context, word string) (*Entry, error) {
+	if err := ValidateWord(word); err != nil {
+		return nil, err
+	}
+	resp, err := upstream.Default.Get(ctx, BaseURL+"/us/dictionary/english/"+url.PathEscape(word))
+	if errors.Is(err, upstream.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := Parse(bytes.NewReader(resp.Body))
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.Blocks) == 0 {
+		// unknown words are redirected to the search page.
+		return nil, ErrNotFound
+	}
+	return entry, nil
+}
+
+var (
+	sectionSelector  = coreHtml.MustCompile("div.pos-header.dpos-h, div.def-block.ddef_block")
+	headerSelector   = coreHtml.MustCompile("div.pos-header.dpos-h")
+	headwordSelector = coreHtml.MustCompile("span.hw.dhw")
+	posSelector      = coreHtml.MustCompile("span.pos.dpos")
+	pronSelector     = coreHtml.MustCompile("span.dpron-i")
+	audioSelector    = coreHtml.MustCompile(`source[type="audio/mpeg"]`)
+	ipaSelector      = coreHtml.MustCompile("span.ipa.dipa")
+	defSelector      = coreHtml.MustCompile("div.def.ddef_d")
+	exampleSelector  = coreHtml.MustCompile("span.eg.deg")
+)
+
+// Parse extracts the entry from a dictionary page. Senses belong to the
+// part of speech header preceding them in the document.
+func Parse(r io.Reader) (*Entry, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{}
+	for _, section := range sectionSelector.QueryAll(doc) {
+		if headerSelector.Match(section) {
+			headword, block := parseHeader(section)
+			if entry.Headword == "" {
+				entry.Headword = headword
+			}
+			entry.Blocks = append(entry.Blocks, block)
+			continue
+		}
+
+		if len(entry.Blocks) == 0 {
+			entry.Blocks = append(entry.Blocks, Block{})
+		}
+		block := &entry.Blocks[len(entry.Blocks)-1]
+		block.Senses = append(block.Senses, parseSense(section))
+	}
+	return entry, nil
+}
+
+func parseHeader(header *html.Node) (string, Block) {
+	var headword string
+	if node := headwordSelector.QueryOne(header); node != nil {
+		headword = coreHtml.Text(node)
+	}
+
+	poses := []string{}
+	for _, node := range posSelector.QueryAll(header) {
+		poses = append(poses, coreHtml.Text(node))
+	}
+
+	block := Block{
+		POS:            strings.Join(poses, ", "),
+		Pronunciations: []Pronunciation{},
+		Senses:         []Sense{},
+	}
+	for _, node := range pronSelector.QueryAll(header) {
+		block.Pronunciations = append(block.Pronunciations, parsePronunciation(node))
+	}
+	return headword, block
+}
+
+func parsePronunciation(pron *html.Node) Pronunciation {
+	result := Pronunciation{}
+	for _, region := range []string{"us", "uk"} {
+		if coreHtml.HasAttr(pron.Attr, "class", region) {
+			result.Region = region
+		}
+	}
+	if node := audioSelector.QueryOne(pron); node != nil {
+		result.AudioURL = coreHtml.GetAttr(node.Attr, "src")
+	}
+	if node := ipaSelector.QueryOne(pron); node != nil {
+		result.IPA = coreHtml.Text(node)
+	}
+	return result
+}
+
+func parseSense(defBlock *html.Node) Sense {
+	sense := Sense{
+		Examples: []string{},
+	}
+	if node := defSelector.QueryOne(defBlock); node != nil {
+		sense.Definition = strings.TrimSpace(strings.TrimSuffix(coreHtml.Text(node), ":"))
+	}
+	for _, node := range exampleSelector.QueryAll(defBlock) {
+		sense.Examples = append(sense.Examples, coreHtml.Text(node))
+	}
+	return sense
+}
@@ -0,0 +1,174 @@
+package dictionary_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	handler "github.com/hnimtadd/spaced/api/sound"
+	pronunciations "github.com/hnimtadd/spaced/api/v1/pronunciations"
+	"github.com/hnimtadd/spaced/internal/golden"
+	"github.com/hnimtadd/spaced/src/dictionary"
+	"github.com/hnimtadd/spaced/src/pronunciation"
+)
+
+// TestScraperGolden parses every saved page, then serves it to the sound
+// handlers for each lookup of testdata/<page>.cases, and compares the whole
+// with testdata/<page>.golden.
+//
+//	go test ./src/dictionary -update  # rewrite the golden files
+func TestScraperGolden(t *testing.T) {
+	s := newScraperSuite(t)
+	golden.Run(t, func(t *testing.T, name string) []byte {
+		return s.run(t, name+".html")
+	})
+}
+
+// scraperSuite serves the saved pages from a local stand-in of the
+// dictionary, so the pronunciation handlers run end to end without the
+// network.
+type scraperSuite struct {
+	upstream *httptest.Server
+	pages    map[string]string // word -> page path
+}
+
+func newScraperSuite(t *testing.T) *scraperSuite {
+	s := &scraperSuite{pages: map[string]string{}}
+	s.upstream = httptest.NewServer(http.HandlerFunc(s.serveUpstream))
+	t.Cleanup(s.upstream.Close)
+	base := dictionary.BaseURL
+	dictionary.BaseURL = s.upstream.URL
+	t.Cleanup(func() { dictionary.BaseURL = base })
+	return s
+}
+
+// serveUpstream answers dictionary pages from testdata and media files with
+// a fake payload naming the requested file.
+func (s *scraperSuite) serveUpstream(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/media/") {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		fmt.Fprintf(w, "mp3:%s", r.URL.Path)
+		return
+	}
+
+	word := strings.TrimPrefix(r.URL.Path, "/us/dictionary/english/")
+	page, exists := s.pages[word]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, page)
+}
+
+func (s *scraperSuite) run(t *testing.T, pagePath string) []byte {
+	t.Helper()
+	word := strings.TrimSuffix(filepath.Base(pagePath), ".html")
+	s.pages[word] = pagePath
+
+	buf := &bytes.Buffer{}
+
+	file, err := os.Open(pagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entry, err := dictionary.Parse(file)
+	if err != nil {
+		t.Fatalf("failed to parse page: %v", err)
+	}
+	entryBytes, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(buf, "# entry\n%s\n", entryBytes)
+
+	cases, err := os.ReadFile(strings.TrimSuffix(pagePath, ".html") + ".cases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for line := range strings.Lines(string(cases)) {
+		region, ipa, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if !found {
+			continue
+		}
+		fmt.Fprintf(buf, "# sound %s %s\n%s\n", region, ipa, s.lookup(word, ipa, region))
+	}
+	fmt.Fprintf(buf, "# sound of unknown word\n%s\n", s.lookup(word+"-unknown", "x", "us"))
+	fmt.Fprintf(buf, "# sound of invalid word\n%s\n", s.lookup("../"+word, "x", "us"))
+	fmt.Fprintf(buf, "# stream range of first sound\n%s\n", s.stream(word, cases))
+	fmt.Fprintf(buf, "# legacy header alias of first sound\n%s\n", s.legacy(word, cases))
+	return buf.Bytes()
+}
+
+// stream asks for the binary sound of the first case with a Range header.
+func (s *scraperSuite) stream(word string, cases []byte) string {
+	region, ipa := firstCase(cases)
+	req := httptest.NewRequest(http.MethodGet, pronunciation.Request{Word: word, IPA: ipa, Region: region}.URL(), http.NoBody)
+	req.Header.Set("Accept", "audio/mpeg")
+	req.Header.Set("Range", "bytes=0-9")
+	rec := httptest.NewRecorder()
+	pronunciations.Handler(rec, req)
+
+	return fmt.Sprintf("%d %s length=%s range=%s etag=%t %q",
+		rec.Code,
+		rec.Header().Get("Content-Type"),
+		rec.Header().Get("Content-Length"),
+		rec.Header().Get("Content-Range"),
+		rec.Header().Get("ETag") != "",
+		rec.Body.String(),
+	)
+}
+
+// legacy calls the deprecated header based handler with the first case.
+func (s *scraperSuite) legacy(word string, cases []byte) string {
+	region, ipa := firstCase(cases)
+	req := httptest.NewRequest(http.MethodGet, pronunciation.LegacyPath, http.NoBody)
+	req.Header.Set(handler.CraftWordHeader, word)
+	req.Header.Set(handler.CraftIPAHeader, base64.StdEncoding.EncodeToString([]byte(ipa)))
+	req.Header.Set(handler.CraftRegionHeader, region)
+	rec := httptest.NewRecorder()
+	handler.Handler(rec, req)
+
+	body := map[string]string{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	sound, _ := base64.StdEncoding.DecodeString(body["payload"])
+	return fmt.Sprintf("%d deprecation=%s code=%q %s", rec.Code, rec.Header().Get("Deprecation"), body["code"], sound)
+}
+
+// lookup calls the pronunciation handler and renders the response,
+// decoding the audio so the golden file shows which media file was picked.
+func (s *scraperSuite) lookup(word, ipa, region string) string {
+	req := httptest.NewRequest(http.MethodGet, pronunciation.Request{Word: word, IPA: ipa, Region: region}.URL(), http.NoBody)
+	rec := httptest.NewRecorder()
+	pronunciations.Handler(rec, req)
+
+	body := struct {
+		Payload *pronunciation.Response `json:"payload"`
+		Error   string                  `json:"error"`
+		Code    string                  `json:"code"`
+	}{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		return fmt.Sprintf("%d %s", rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	if body.Payload == nil {
+		return fmt.Sprintf("%d %s: %s", rec.Code, body.Code, body.Error)
+	}
+	sound, err := base64.StdEncoding.DecodeString(body.Payload.Audio)
+	if err != nil {
+		return fmt.Sprintf("%d invalid audio: %v", rec.Code, err)
+	}
+	return fmt.Sprintf("%d %s %s", rec.Code, body.Payload.Source, sound)
+}
+
+func firstCase(cases []byte) (string, string) {
+	line, _, _ := strings.Cut(string(cases), "\n")
+	region, ipa, _ := strings.Cut(line, "\t")
+	return region, ipa
+}
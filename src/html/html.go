@@ -32,3 +32,16 @@ func HasAttr(attrs []coreHtml.Attribute, key string, values ...string) bool {
 	}
 	return false
 }
+
+// Text returns the text content of node and its descendants with the
+// whitespace collapsed.
+func Text(node *coreHtml.Node) string {
+	buf := &strings.Builder{}
+	_ = Walk(node, func(node *coreHtml.Node) error {
+		if node.Type == coreHtml.TextNode {
+			buf.WriteString(node.Data)
+		}
+		return nil
+	})
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
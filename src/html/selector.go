@@ -0,0 +1,461 @@
+package html
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	coreHtml "golang.org/x/net/html"
+)
+
+// Selector is a compiled CSS selector. The supported syntax is a subset of
+// CSS level 3:
+//
+//	tag, *                  type and universal selectors
+//	.class, #id             class and id selectors
+//	[attr], [attr=value]    attribute presence and equality, value may be quoted
+//	:nth-child(an+b)        also odd, even and plain numbers
+//	a b, a > b              descendant and child combinators
+//	a, b                    selector groups
+type Selector struct {
+	source string
+	groups []complexSelector
+}
+
+// complexSelector is a chain of compound selectors, parts[i] is joined to
+// parts[i+1] by combinators[i].
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+type compoundSelector struct {
+	tag      string
+	id       string
+	classes  []string
+	attrs    []attrSelector
+	nthChild *nth
+}
+
+type attrSelector struct {
+	key    string
+	value  string
+	hasVal bool
+}
+
+// nth matches the elements whose 1-based index is a*n+b for some n >= 0.
+type nth struct {
+	a, b int
+}
+
+const (
+	combinatorDescendant byte = ' '
+	combinatorChild      byte = '>'
+)
+
+// Compile parses a selector.
+func Compile(selector string) (*Selector, error) {
+	p := &selectorParser{src: selector}
+	groups, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return &Selector{source: selector, groups: groups}, nil
+}
+
+// MustCompile is like Compile but panics if the selector cannot be parsed.
+func MustCompile(selector string) *Selector {
+	s, err := Compile(selector)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Selector) String() string { return s.source }
+
+// Match reports whether node matches any group of the selector.
+func (s *Selector) Match(node *coreHtml.Node) bool {
+	if node.Type != coreHtml.ElementNode {
+		return false
+	}
+	for _, group := range s.groups {
+		if group.match(node, len(group.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryAll returns the descendants of node matching the selector in
+// document order.
+func (s *Selector) QueryAll(node *coreHtml.Node) []*coreHtml.Node {
+	matches := []*coreHtml.Node{}
+	for child := range node.Descendants() {
+		if s.Match(child) {
+			matches = append(matches, child)
+		}
+	}
+	return matches
+}
+
+// QueryOne returns the first descendant of node matching the selector, or
+// nil if there is none.
+func (s *Selector) QueryOne(node *coreHtml.Node) *coreHtml.Node {
+	for child := range node.Descendants() {
+		if s.Match(child) {
+			return child
+		}
+	}
+	return nil
+}
+
+var selectorCache sync.Map // string -> *Selector
+
+func cachedSelector(selector string) *Selector {
+	if s, ok := selectorCache.Load(selector); ok {
+		return s.(*Selector)
+	}
+	s := MustCompile(selector)
+	selectorCache.Store(selector, s)
+	return s
+}
+
+// QueryAll returns the descendants of node matching selector, it panics if
+// selector is invalid, use Compile for selectors built at runtime.
+func QueryAll(node *coreHtml.Node, selector string) []*coreHtml.Node {
+	return cachedSelector(selector).QueryAll(node)
+}
+
+// QueryOne returns the first descendant of node matching selector, it panics
+// if selector is invalid, use Compile for selectors built at runtime.
+func QueryOne(node *coreHtml.Node, selector string) *coreHtml.Node {
+	return cachedSelector(selector).QueryOne(node)
+}
+
+// match reports whether node matches parts[0..idx], walking up the tree for
+// the combinators.
+func (c complexSelector) match(node *coreHtml.Node, idx int) bool {
+	if !c.parts[idx].match(node) {
+		return false
+	}
+	if idx == 0 {
+		return true
+	}
+
+	switch c.combinators[idx-1] {
+	case combinatorChild:
+		parent := node.Parent
+		return parent != nil && parent.Type == coreHtml.ElementNode && c.match(parent, idx-1)
+	default:
+		for ancestor := node.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			if ancestor.Type == coreHtml.ElementNode && c.match(ancestor, idx-1) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (c compoundSelector) match(node *coreHtml.Node) bool {
+	if node.Type != coreHtml.ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != "*" && c.tag != node.Data {
+		return false
+	}
+	if c.id != "" && GetAttr(node.Attr, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(GetAttr(node.Attr, "class"))
+		for _, class := range c.classes {
+			found := false
+			for _, candidate := range classes {
+				if candidate == class {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.match(node) {
+			return false
+		}
+	}
+	if c.nthChild != nil && !c.nthChild.match(childIndex(node)) {
+		return false
+	}
+	return true
+}
+
+func (a attrSelector) match(node *coreHtml.Node) bool {
+	for _, attr := range node.Attr {
+		if attr.Key != a.key {
+			continue
+		}
+		return !a.hasVal || attr.Val == a.value
+	}
+	return false
+}
+
+func (n nth) match(idx int) bool {
+	if n.a == 0 {
+		return idx == n.b
+	}
+	diff := idx - n.b
+	return diff%n.a == 0 && diff/n.a >= 0
+}
+
+// childIndex returns the 1-based position of node among its element siblings.
+func childIndex(node *coreHtml.Node) int {
+	idx := 1
+	for sibling := node.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == coreHtml.ElementNode {
+			idx++
+		}
+	}
+	return idx
+}
+
+type selectorParser struct {
+	src string
+	pos int
+}
+
+func (p *selectorParser) parse() ([]complexSelector, error) {
+	groups := []complexSelector{}
+	for {
+		p.skipSpaces()
+		group, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+
+		p.skipSpaces()
+		if p.eof() {
+			return groups, nil
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("unexpected %q at %d", p.peek(), p.pos)
+		}
+		p.pos++
+	}
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	c := complexSelector{}
+	for {
+		part, err := p.parseCompound()
+		if err != nil {
+			return c, err
+		}
+		c.parts = append(c.parts, part)
+
+		hadSpace := p.skipSpaces()
+		if p.eof() || p.peek() == ',' {
+			return c, nil
+		}
+		switch p.peek() {
+		case '>':
+			p.pos++
+			p.skipSpaces()
+			c.combinators = append(c.combinators, combinatorChild)
+		default:
+			if !hadSpace {
+				return c, fmt.Errorf("unexpected %q at %d", p.peek(), p.pos)
+			}
+			c.combinators = append(c.combinators, combinatorDescendant)
+		}
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	c := compoundSelector{}
+	start := p.pos
+	if !p.eof() && p.peek() == '*' {
+		p.pos++
+		c.tag = "*"
+	} else if name := p.parseIdent(); name != "" {
+		c.tag = strings.ToLower(name)
+	}
+
+	for !p.eof() {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			name := p.parseIdent()
+			if name == "" {
+				return c, fmt.Errorf("expected class name at %d", p.pos)
+			}
+			c.classes = append(c.classes, name)
+		case '#':
+			p.pos++
+			name := p.parseIdent()
+			if name == "" {
+				return c, fmt.Errorf("expected id at %d", p.pos)
+			}
+			c.id = name
+		case '[':
+			attr, err := p.parseAttr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, attr)
+		case ':':
+			n, err := p.parsePseudo()
+			if err != nil {
+				return c, err
+			}
+			c.nthChild = n
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("expected selector at %d", p.pos)
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, fmt.Errorf("expected selector at %d", p.pos)
+	}
+	return c, nil
+}
+
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	attr := attrSelector{}
+	p.pos++ // [
+	p.skipSpaces()
+	attr.key = strings.ToLower(p.parseIdent())
+	if attr.key == "" {
+		return attr, fmt.Errorf("expected attribute name at %d", p.pos)
+	}
+	p.skipSpaces()
+	if p.eof() {
+		return attr, fmt.Errorf("unterminated attribute selector")
+	}
+	if p.peek() == '=' {
+		p.pos++
+		p.skipSpaces()
+		value, err := p.parseValue()
+		if err != nil {
+			return attr, err
+		}
+		attr.value = value
+		attr.hasVal = true
+		p.skipSpaces()
+	}
+	if p.eof() || p.peek() != ']' {
+		return attr, fmt.Errorf("expected ] at %d", p.pos)
+	}
+	p.pos++
+	return attr, nil
+}
+
+func (p *selectorParser) parseValue() (string, error) {
+	if p.eof() {
+		return "", fmt.Errorf("expected attribute value at %d", p.pos)
+	}
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		return p.parseIdent(), nil
+	}
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at %d", p.pos)
+	}
+	value := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return value, nil
+}
+
+func (p *selectorParser) parsePseudo() (*nth, error) {
+	p.pos++ // :
+	name := p.parseIdent()
+	if name != "nth-child" {
+		return nil, fmt.Errorf("unsupported pseudo-class %q", name)
+	}
+	if p.eof() || p.peek() != '(' {
+		return nil, fmt.Errorf("expected ( at %d", p.pos)
+	}
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], ')')
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated :nth-child")
+	}
+	expr := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return parseNth(expr)
+}
+
+// parseNth parses the an+b notation of :nth-child.
+func parseNth(expr string) (*nth, error) {
+	expr = strings.ToLower(strings.ReplaceAll(expr, " ", ""))
+	switch expr {
+	case "odd":
+		return &nth{a: 2, b: 1}, nil
+	case "even":
+		return &nth{a: 2, b: 0}, nil
+	}
+
+	nIdx := strings.IndexByte(expr, 'n')
+	if nIdx < 0 {
+		b, err := strconv.Atoi(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid :nth-child(%s)", expr)
+		}
+		return &nth{b: b}, nil
+	}
+
+	result := &nth{}
+	switch coef := expr[:nIdx]; coef {
+	case "", "+":
+		result.a = 1
+	case "-":
+		result.a = -1
+	default:
+		a, err := strconv.Atoi(coef)
+		if err != nil {
+			return nil, fmt.Errorf("invalid :nth-child(%s)", expr)
+		}
+		result.a = a
+	}
+	if rest := expr[nIdx+1:]; rest != "" {
+		b, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid :nth-child(%s)", expr)
+		}
+		result.b = b
+	}
+	return result, nil
+}
+
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+	for _, r := range p.src[p.pos:] {
+		if r != '-' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		p.pos += len(string(r))
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *selectorParser) skipSpaces() bool {
+	start := p.pos
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\n') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) peek() byte { return p.src[p.pos] }
+
+func (p *selectorParser) eof() bool { return p.pos >= len(p.src) }
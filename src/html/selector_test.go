@@ -0,0 +1,66 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hnimtadd/spaced/internal/golden"
+	coreHtml "golang.org/x/net/html"
+)
+
+// TestSelectorsGolden runs testdata/<page>.queries against every saved page
+// and compares the matches with testdata/<page>.golden.
+//
+//	go test ./src/html -update  # rewrite the golden files
+func TestSelectorsGolden(t *testing.T) {
+	golden.Run(t, func(t *testing.T, name string) []byte {
+		return runQueries(t, name+".html", name+".queries")
+	})
+}
+
+func runQueries(t *testing.T, pagePath, queriesPath string) []byte {
+	t.Helper()
+	file, err := os.Open(pagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	doc, err := coreHtml.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries, err := os.ReadFile(queriesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	for line := range strings.Lines(string(queries)) {
+		query := strings.TrimSpace(line)
+		if query == "" {
+			continue
+		}
+		fmt.Fprintf(buf, "# %s\n", query)
+		selector, err := Compile(query)
+		if err != nil {
+			fmt.Fprintf(buf, "error: %v\n", err)
+			continue
+		}
+		for _, node := range selector.QueryAll(doc) {
+			fmt.Fprintf(buf, "%s %q\n", describe(node), Text(node))
+		}
+	}
+	return buf.Bytes()
+}
+
+// describe renders the opening tag of node.
+func describe(node *coreHtml.Node) string {
+	parts := []string{node.Data}
+	for _, attr := range node.Attr {
+		parts = append(parts, fmt.Sprintf("%s=%q", attr.Key, attr.Val))
+	}
+	return "<" + strings.Join(parts, " ") + ">"
+}
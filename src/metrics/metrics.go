@@ -0,0 +1,275 @@
+// Package metrics keeps counters, gauges and histograms in memory and writes
+// them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds the metrics written by WriteText, in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// Default is the registry served by cmd/server on /metrics.
+var Default = NewRegistry()
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric of the registry.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// series are the values of a metric per label values.
+type series[T any] struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]*T
+	new    func() *T
+}
+
+// init creates the only series of a metric without labels, so it is written
+// out before its first update.
+func (s *series[T]) init() {
+	if len(s.labels) == 0 {
+		s.with(nil)
+	}
+}
+
+func (s *series[T]) with(values []string) *T {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(s.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		v = s.new()
+		s.values[key] = v
+	}
+	return v
+}
+
+// each calls fn with the label pairs of every series, sorted.
+func (s *series[T]) each(fn func(labels string, v *T)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]*T, len(keys))
+	for i, key := range keys {
+		values[i] = s.values[key]
+	}
+	s.mu.Unlock()
+
+	for i, key := range keys {
+		pairs := []string{}
+		if len(s.labels) > 0 {
+			for j, value := range strings.Split(key, "\xff") {
+				pairs = append(pairs, s.labels[j]+`="`+escaper.Replace(value)+`"`)
+			}
+		}
+		fn(strings.Join(pairs, ","), values[i])
+	}
+}
+
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(delta float64) {
+	v.mu.Lock()
+	v.v += delta
+	v.mu.Unlock()
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+// Counter is a value which only goes up, per label values.
+type Counter struct {
+	name, help string
+	series     series[value]
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, series: series[value]{
+		labels: labels,
+		values: map[string]*value{},
+		new:    func() *value { return &value{} },
+	}}
+	c.series.init()
+	r.register(name, c)
+	return c
+}
+
+func (c *Counter) Inc(labels ...string) { c.Add(1, labels...) }
+
+func (c *Counter) Add(delta float64, labels ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.name + " cannot decrease")
+	}
+	c.series.with(labels).add(delta)
+}
+
+func (c *Counter) write(w io.Writer) {
+	header(w, c.name, c.help, "counter")
+	c.series.each(func(labels string, v *value) {
+		sample(w, c.name, labels, v.get())
+	})
+}
+
+// Gauge is a value which goes up and down, per label values.
+type Gauge struct {
+	name, help string
+	series     series[value]
+}
+
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{name: name, help: help, series: series[value]{
+		labels: labels,
+		values: map[string]*value{},
+		new:    func() *value { return &value{} },
+	}}
+	g.series.init()
+	r.register(name, g)
+	return g
+}
+
+func (g *Gauge) Add(delta float64, labels ...string) { g.series.with(labels).add(delta) }
+
+func (g *Gauge) write(w io.Writer) {
+	header(w, g.name, g.help, "gauge")
+	g.series.each(func(labels string, v *value) {
+		sample(w, g.name, labels, v.get())
+	})
+}
+
+// Histogram counts observations in cumulative buckets, per label values.
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	series     series[histogram]
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// DefaultBuckets suit request latencies in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{name: name, help: help, buckets: buckets, series: series[histogram]{
+		labels: labels,
+		values: map[string]*histogram{},
+		new:    func() *histogram { return &histogram{counts: make([]uint64, len(buckets))} },
+	}}
+	h.series.init()
+	r.register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	s := h.series.with(labels)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w io.Writer) {
+	header(w, h.name, h.help, "histogram")
+	h.series.each(func(labels string, s *histogram) {
+		s.mu.Lock()
+		counts := append([]uint64{}, s.counts...)
+		count, sum := s.count, s.sum
+		s.mu.Unlock()
+
+		for i, bound := range h.buckets {
+			sample(w, h.name+"_bucket", join(labels, `le="`+formatFloat(bound)+`"`), float64(counts[i]))
+		}
+		sample(w, h.name+"_bucket", join(labels, `le="+Inf"`), float64(count))
+		sample(w, h.name+"_sum", labels, sum)
+		sample(w, h.name+"_count", labels, float64(count))
+	})
+}
+
+// escaper escapes label values the way the exposition format expects.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func header(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sample(w io.Writer, name, labels string, v float64) {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+}
+
+func join(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
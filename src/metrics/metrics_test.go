@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests served.", "path")
+	r.Counter("errors_total", "Errors, written before the first one.")
+	inFlight := r.Gauge("in_flight", "Requests being served.")
+	latency := r.Histogram("latency_seconds", "Time to serve.", []float64{1, 0.5}, "method")
+
+	requests.Add(2, "/plain")
+	requests.Inc("/a\"b\\c\n")
+	inFlight.Add(3)
+	inFlight.Add(-1)
+	for _, v := range []float64{0.25, 0.5, 0.75, 2} {
+		latency.Observe(v, "GET")
+	}
+
+	want := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{path="/a\"b\\c\n"} 1
+requests_total{path="/plain"} 2
+# HELP errors_total Errors, written before the first one.
+# TYPE errors_total counter
+errors_total 0
+# HELP in_flight Requests being served.
+# TYPE in_flight gauge
+in_flight 2
+# HELP latency_seconds Time to serve.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.5"} 2
+latency_seconds_bucket{method="GET",le="1"} 3
+latency_seconds_bucket{method="GET",le="+Inf"} 4
+latency_seconds_sum{method="GET"} 3.5
+latency_seconds_count{method="GET"} 4
+`
+	var out strings.Builder
+	r.WriteText(&out)
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	r := NewRegistry()
+	counter := r.Counter("requests_total", "Requests served.", "path")
+	for _, tt := range []struct {
+		name string
+		fn   func()
+	}{
+		{"duplicate name", func() { r.Gauge("requests_total", "Again.") }},
+		{"missing label", func() { counter.Inc() }},
+		{"extra label", func() { counter.Inc("/", "GET") }},
+		{"decreasing counter", func() { counter.Add(-1, "/") }},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", tt.name)
+				}
+			}()
+			tt.fn()
+		}()
+	}
+}
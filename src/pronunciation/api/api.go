@@ -0,0 +1,32 @@
+// Package api is the wire contract of the pronunciation endpoints, it has no
+// dependencies so the wasm modules can build requests without pulling in the
+// dictionary scraper.
+package api
+
+import "net/url"
+
+const (
+	DefaultRegion = "us"
+
+	// Path is the versioned endpoint, LegacyPath the deprecated header based
+	// one it replaces.
+	Path       = "/api/v1/pronunciations"
+	LegacyPath = "/api/sound/index"
+)
+
+// Request identifies the pronunciation to fetch, IPA picks among the
+// flavors of a word.
+type Request struct {
+	Word   string `json:"word"`
+	IPA    string `json:"ipa"`
+	Region string `json:"region"`
+}
+
+// URL returns the versioned endpoint address of req.
+func (req Request) URL() string {
+	query := url.Values{}
+	query.Set("word", req.Word)
+	query.Set("ipa", req.IPA)
+	query.Set("region", req.Region)
+	return Path + "?" + query.Encode()
+}
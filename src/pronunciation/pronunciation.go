@@ -0,0 +1,138 @@
+package pronunciation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hnimtadd/spaced/src/dictionary"
+	"github.com/hnimtadd/spaced/src/pronunciation/api"
+	"github.com/hnimtadd/spaced/src/upstream"
+	"github.com/hnimtadd/spaced/src/utils"
+)
+
+const (
+	DefaultRegion = api.DefaultRegion
+	Path          = api.Path
+	LegacyPath    = api.LegacyPath
+)
+
+// MaxIPALength is the longest IPA, in runes, a request may carry.
+const MaxIPALength = 64
+
+var ErrInvalidRequest = errors.New("invalid pronunciation request")
+
+// Request identifies the pronunciation to fetch, see api.Request.
+type Request = api.Request
+
+// Response is the JSON body of a successful request, Audio is the base64
+// encoded audio/mpeg sound.
+type Response struct {
+	Word   string `json:"word"`
+	IPA    string `json:"ipa"`
+	Region string `json:"region"`
+	Source string `json:"source"`
+	Audio  string `json:"audio"`
+}
+
+// Pronunciation is a resolved request.
+type Pronunciation struct {
+	Request
+	Source string
+	Sound  []byte
+}
+
+// RequestFromQuery reads ?word=&ipa=&region=.
+func RequestFromQuery(query url.Values) (Request, error) {
+	req := Request{
+		Word:   strings.TrimSpace(query.Get("word")),
+		IPA:    strings.TrimSpace(query.Get("ipa")),
+		Region: strings.TrimSpace(query.Get("region")),
+	}
+	return req, validate(&req)
+}
+
+func validate(req *Request) error {
+	if req.Region == "" {
+		req.Region = DefaultRegion
+	}
+	if req.Word == "" || req.IPA == "" {
+		return fmt.Errorf("%w: word and ipa are required", ErrInvalidRequest)
+	}
+	if req.Region != "us" && req.Region != "uk" {
+		return fmt.Errorf("%w: region must be us or uk", ErrInvalidRequest)
+	}
+	if err := dictionary.ValidateWord(req.Word); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if utf8.RuneCountInString(req.IPA) > MaxIPALength {
+		return fmt.Errorf("%w: ipa longer than %d characters", ErrInvalidRequest, MaxIPALength)
+	}
+	return nil
+}
+
+// Resolve finds the pronunciation in the dictionary and downloads it.
+func Resolve(ctx context.Context, req Request) (*Pronunciation, error) {
+	entry, err := dictionary.Lookup(ctx, req.Word)
+	if err != nil {
+		return nil, err
+	}
+	source, err := entry.SoundURL(req.IPA, req.Region)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := upstream.Default.Get(ctx, dictionary.BaseURL+source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download sound: %w", err)
+	}
+	return &Pronunciation{
+		Request: req,
+		Source:  source,
+		Sound:   resp.Body,
+	}, nil
+}
+
+// WriteError answers err with the status and code every pronunciation
+// endpoint shares.
+func WriteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		utils.SError(w, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, dictionary.ErrNotFound):
+		utils.SError(w, http.StatusNotFound, "not_found", dictionary.ErrNotFound.Error())
+	case errors.Is(err, dictionary.ErrNoSound):
+		utils.SError(w, http.StatusNotFound, "sound_not_found", err.Error())
+	default:
+		status, code := upstream.Classify(err)
+		utils.SError(w, status, code, err.Error())
+	}
+}
+
+// AcceptsAudio reports whether the client asked for the raw sound instead
+// of JSON.
+func AcceptsAudio(r *http.Request) bool {
+	for accept := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(accept), ";")
+		if mediaType == "audio/mpeg" || mediaType == "audio/*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeAudio writes the sound as audio/mpeg, http.ServeContent takes care of
+// Content-Length, Range and the If-None-Match check against the ETag.
+func ServeAudio(w http.ResponseWriter, r *http.Request, p *Pronunciation) {
+	sum := sha256.Sum256(p.Sound)
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, p.Word+".mp3", time.Time{}, bytes.NewReader(p.Sound))
+}
@@ -0,0 +1,102 @@
+package review
+
+import (
+	"context"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+	crafterhttp "github.com/hnimtadd/spaced/src/crafter/http"
+	"github.com/hnimtadd/spaced/src/crafter/render"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+// DefaultDeck is the id of the deck served at DeckURL, the only one so far.
+const DefaultDeck = "default"
+
+// App is the single page behind every path of the ui. One module serves them
+// all, so the manager and the state it loaded outlive the navigation.
+type App struct {
+	Manager *Manager
+	Stats   *Stats
+	Router  *crafter.Router
+	// Store holds the session of the manager for the session page: "card"
+	// is the card under review, "remaining" and "reviewed" count the cards
+	// of the session, "suggested" is the rating suggested for a slow answer.
+	// "shortcuts" follows Keymap.
+	Store  *crafter.Store
+	Keymap *crafter.Keymap
+}
+
+// cardInput reads the card under review from the session page.
+const cardInput = "#flashcard:[data-card]"
+
+// Shortcuts of the session page, the keys may be changed in the settings.
+var Shortcuts = []crafter.Shortcut{
+	{Action: "reveal", Key: "space", Scope: "/session"},
+	{Action: "rate-again", Key: "1", Scope: "/session", Handler: "submit", Input: cardInput, Args: []any{fsrs.Again}},
+	{Action: "rate-hard", Key: "2", Scope: "/session", Handler: "submit", Input: cardInput, Args: []any{fsrs.Hard}},
+	{Action: "rate-good", Key: "3", Scope: "/session", Handler: "submit", Input: cardInput, Args: []any{fsrs.Good}},
+	{Action: "rate-easy", Key: "4", Scope: "/session", Handler: "submit", Input: cardInput, Args: []any{fsrs.Easy}},
+	{Action: "undo", Key: "u", Scope: "/session", Handler: "undo"},
+	{Action: "play", Key: "p", Scope: "/session", Handler: "play", Input: cardInput},
+	{Action: "suspend", Key: "s", Scope: "/session", Handler: "suspend", Input: cardInput},
+}
+
+func NewApp(platform crafter.Platform, events *crafter.Events, client *crafterhttp.Client) *App {
+	a := &App{
+		Manager: NewManager(platform, events, client),
+		Stats:   NewStats(platform, events),
+		Router:  crafter.NewRouter(platform),
+		Store:   crafter.NewStore(events),
+		Keymap:  crafter.NewKeymap(platform.Storage(), Shortcuts...),
+	}
+	crafter.Bind(a.Store, "shortcuts", a.Keymap)
+	m := a.Manager
+	crafter.Bind(a.Store, "card", m.current)
+	crafter.Bind(a.Store, "remaining", m.remaining)
+	crafter.Bind(a.Store, "reviewed", crafter.NewComputed(func() int {
+		return m.size.Get() - m.remaining.Get()
+	}, m.size, m.remaining))
+	crafter.Bind(a.Store, "suggested", m.suggested)
+
+	a.Router.Handle("/", page(func(crafter.Route) (render.Component, error) {
+		return homeView{Deck: DefaultDeck}, nil
+	}))
+	a.Router.Handle("/session", page(func(crafter.Route) (render.Component, error) {
+		return sessionView{}, nil
+	}))
+	a.Router.Handle("/stats", page(func(crafter.Route) (render.Component, error) {
+		return statsPageView{}, nil
+	}))
+	a.Router.Handle("/decks/:id", page(a.deck))
+	a.Router.Handle("/settings", page(func(crafter.Route) (render.Component, error) {
+		return settingsView{Shortcuts: a.Keymap.Get()}, nil
+	}))
+	a.Router.NotFound(page(func(crafter.Route) (render.Component, error) {
+		return notFoundView{}, nil
+	}))
+	return a
+}
+
+// page renders the component of a route with the views of the package.
+func page(fn func(route crafter.Route) (render.Component, error)) crafter.ViewFunc {
+	return func(_ context.Context, route crafter.Route) (string, error) {
+		c, err := fn(route)
+		if err != nil {
+			return "", err
+		}
+		html, err := views.HTML(c)
+		return string(html), err
+	}
+}
+
+// deck lists the cards of a deck with their next review.
+func (a *App) deck(route crafter.Route) (render.Component, error) {
+	if id := route.Params["id"]; id != DefaultDeck {
+		return nil, crafter.Errorf(crafter.CodeNotFound, "no deck %s", id)
+	}
+	cards := a.Manager.Cards()
+	if len(cards) == 0 {
+		return nil, crafter.Errorf(crafter.CodeNotReady, "no cards found")
+	}
+	return deckView{Cards: cards}, nil
+}
@@ -0,0 +1,90 @@
+package review_test
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+// TestNavigate renders the pages in place and pushes them to the history.
+func TestNavigate(t *testing.T) {
+	f := newFixture(t)
+	f.start(t)
+	if got := call(t, f.app.Manager.Suspend, strconv.Itoa(f.next(t).ID)); got != `{"success":true,"payload":"suspended"}` {
+		t.Fatalf("suspend: got %s", got)
+	}
+
+	tcs := []struct {
+		name    string
+		args    []string
+		pattern string
+		markup  string
+	}{
+		{"stats", []string{`"/stats"`}, "/stats", `craft-name="stats"`},
+		{"deck", []string{`"/decks/default"`}, "/decks/:id", "suspended"},
+		{"home", []string{`"/"`}, "/", `href="/decks/default"`},
+		{"home again", []string{`"/"`}, "/", "Welcome"},
+	}
+	for _, tc := range tcs {
+		page := navigate(t, f, tc.args...)
+		if page.Pattern != tc.pattern || !strings.Contains(page.HTML, tc.markup) {
+			t.Errorf("%s: expected %s with %q, got %+v", tc.name, tc.pattern, tc.markup, page)
+		}
+	}
+	if got := f.page.History(); !slices.Equal(got, []string{
+		"https://spaced.test/stats", "https://spaced.test/decks/default", "https://spaced.test/",
+	}) {
+		t.Errorf("unexpected history %v", got)
+	}
+
+	if got := callAsync(t, f.app.Router.Navigate, `"/decks/other"`); got != `{"success":false,"error":"no deck other","code":"not_found"}` {
+		t.Errorf("unknown deck: got %s", got)
+	}
+	// the page loads the paths without route itself.
+	if got := callAsync(t, f.app.Router.Navigate, `"/reset"`); got != `{"success":false,"error":"no route for /reset","code":"not_found"}` {
+		t.Errorf("path without route: got %s", got)
+	}
+	if page := navigate(t, f, `"/reset"`, `true`); page.Pattern != "" || !strings.Contains(page.HTML, "Page not found") {
+		t.Errorf("loaded path without route: got %+v", page)
+	}
+	if got := f.page.History(); !slices.Equal(got, []string{
+		"https://spaced.test/stats", "https://spaced.test/decks/default", "https://spaced.test/reset",
+	}) {
+		t.Errorf("expected the last entry replaced, got %v", got)
+	}
+}
+
+// TestSettings changes the keys of the shortcuts.
+func TestSettings(t *testing.T) {
+	f := newFixture(t)
+	if page := navigate(t, f, `"/settings"`); !strings.Contains(page.HTML, `id="shortcut-rate-again-key" value="1"`) {
+		t.Errorf("expected the default key, got %s", page.HTML)
+	}
+	if got := call(t, f.app.Keymap.Rebind, `"rate-again"`, `"A"`); got != `{"success":true,"payload":"saved"}` {
+		t.Errorf("rebind: got %s", got)
+	}
+	if got := call(t, f.app.Keymap.Rebind, `"undo"`, `"3"`); got != `{"success":false,"error":"key \"3\" is used by rate-good","code":"invalid_arguments"}` {
+		t.Errorf("rebind conflict: got %s", got)
+	}
+	if got := f.stored(t, "shortcuts"); !strings.Contains(got, `{"action":"rate-again","key":"a","scope":"/session","handler":"submit","input":"#flashcard:[data-card]","args":[1]}`) {
+		t.Errorf("expected the key stored, got %s", got)
+	}
+	if page := navigate(t, f, `"/settings"`); !strings.Contains(page.HTML, `id="shortcut-rate-again-key" value="a"`) {
+		t.Errorf("expected the key kept, got %s", page.HTML)
+	}
+}
+
+func navigate(t *testing.T, f *fixture, args ...string) crafter.Page {
+	t.Helper()
+	env := crafter.Invoke(func(req crafter.NavigateRequest) (crafter.Page, error) {
+		return f.app.Router.Navigate(t.Context(), req)
+	}, raw(args))
+	page, ok := env.Payload.(crafter.Page)
+	if !env.Success || !ok {
+		t.Fatalf("navigate %v: got %+v", args, env)
+	}
+	return page
+}
@@ -0,0 +1,791 @@
+// Package review is the logic of the wasm modules behind the session and the
+// stats pages, it runs on a crafter.Platform so it builds natively too.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/core/audio"
+	internalfsrs "github.com/hnimtadd/spaced/src/core/fsrs"
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/core/session"
+	"github.com/hnimtadd/spaced/src/crafter"
+	crafterhttp "github.com/hnimtadd/spaced/src/crafter/http"
+	"github.com/hnimtadd/spaced/src/pronunciation/api"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+// DefaultRegion is the accent used when fetching pronunciations.
+const DefaultRegion = api.DefaultRegion
+
+// DeckURL serves the cards seeding the storage.
+const DeckURL = "/assets/cards.json"
+
+// DefaultSlowAnswer is how long an answer takes before recall counts as
+// slow.
+const DefaultSlowAnswer = 8 * time.Second
+
+// Manager runs the review sessions of the session page over the cards and
+// records kept in the storage of the page.
+type Manager struct {
+	// Now is the clock of the reviews.
+	Now func() time.Time
+	// SlowAnswer is how long a card stays unrated before Hard is suggested,
+	// never when zero.
+	SlowAnswer time.Duration
+
+	platform crafter.Platform
+	events   *crafter.Events
+	client   *crafterhttp.Client
+	audio    *audio.Store
+
+	// mu guards the state below, the handlers and the work they leave in
+	// the background share it. It is never held across a request: in the
+	// browser a sync handler waiting for it would block the event loop the
+	// request needs. Nor while notifying the page, whose listeners may call
+	// back into the manager: the events and signal values are queued in
+	// outbox under mu and sent by unlock.
+	mu       sync.Mutex
+	outbox   []func()
+	flushing bool
+
+	cards       internalfsrs.Cards
+	cardsLookup map[int]*model.Card
+	fsrs        *fsrs.FSRS
+
+	// loaded is the status of the first Load, the state stays in memory
+	// from then on.
+	loaded string
+
+	targetNum   int
+	currSession *session.Session
+	// undo restores the card before the last submit of the session.
+	undo *undo
+	// sounds are the downloads of sounds in flight, by card and region.
+	sounds map[string]*soundCall
+
+	// card is the one on screen.
+	card *model.Card
+	// the session as the page shows it, App binds them to the store.
+	current   *crafter.Signal[*model.Card]
+	size      *crafter.Signal[int]
+	remaining *crafter.Signal[int]
+	// suggested is the rating suggested for the current card, zero for
+	// none. slow sets it once the answer is slow.
+	suggested *crafter.Signal[fsrs.Rating]
+	slow      *time.Timer
+
+	records       []*session.Record
+	recordsLookup map[int]*session.Record
+}
+
+func NewManager(platform crafter.Platform, events *crafter.Events, client *crafterhttp.Client) *Manager {
+	fsrss := fsrs.NewFSRS(fsrs.DefaultParam())
+	m := &Manager{
+		Now:           time.Now,
+		SlowAnswer:    DefaultSlowAnswer,
+		platform:      platform,
+		events:        events,
+		client:        client,
+		audio:         audio.NewStore(platform.Storage(), audio.DefaultLimit),
+		sounds:        map[string]*soundCall{},
+		fsrs:          fsrss,
+		targetNum:     10,
+		records:       []*session.Record{},
+		cardsLookup:   map[int]*model.Card{},
+		recordsLookup: map[int]*session.Record{},
+		// the card is updated in place, every show publishes it again.
+		current:   crafter.NewSignalFunc[*model.Card](nil, nil),
+		size:      crafter.NewSignal(0),
+		remaining: crafter.NewSignal(0),
+		suggested: crafter.NewSignal[fsrs.Rating](0),
+	}
+	// the page is about to go away, keep the ratings given so far.
+	events.On("page:hidden", func(json.RawMessage) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.currSession == nil {
+			return
+		}
+		if err := m.handleSaveState(); err != nil {
+			fmt.Println("failed to save state:", err)
+		}
+	})
+	return m
+}
+
+// Load restores the state of the previous visits, or seeds it from the deck
+// on the first one. Later calls keep the state in memory.
+func (m *Manager) Load(ctx context.Context, _ struct{}) (string, error) {
+	m.mu.Lock()
+	if m.loaded != "" {
+		defer m.mu.Unlock()
+		return m.loaded, nil
+	}
+	restored := m.parsedFromLocalState() == nil
+	m.mu.Unlock()
+
+	var deck internalfsrs.Cards
+	if !restored {
+		cards, err := m.fetchDeck(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to load cards: %w", err)
+		}
+		deck = cards
+	}
+
+	m.mu.Lock()
+	defer m.unlock()
+	// another call loaded the state meanwhile.
+	if m.loaded != "" {
+		return m.loaded, nil
+	}
+	status := "restored"
+	if !restored {
+		m.cards = deck
+		// hack, indexing cards on init
+		for i := range m.cards {
+			m.cards[i].ID = i
+		}
+		if err := m.handleSaveState(); err != nil {
+			return "", err
+		}
+		status = "loaded"
+	}
+
+	for card := range slices.Values(m.cards) {
+		m.cardsLookup[card.ID] = card
+	}
+
+	for record := range slices.Values(m.records) {
+		m.recordsLookup[record.ID] = record
+	}
+	m.emit("cards:loaded", cardsLoaded{Count: len(m.cards), Seeded: status == "loaded"})
+
+	if status == "restored" {
+		go m.syncDeck()
+	}
+	m.loaded = status
+	return status, nil
+}
+
+// Cards returns a copy of the cards of the deck.
+func (m *Manager) Cards() []*model.Card {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cards := make([]*model.Card, len(m.cards))
+	for i, card := range m.cards {
+		copied := *card
+		cards[i] = &copied
+	}
+	return cards
+}
+
+// emit queues an event to the page, unlock sends it. A failure only loses
+// the notification.
+func (m *Manager) emit(name string, payload any) {
+	m.outbox = append(m.outbox, func() {
+		if err := m.events.Emit(name, payload); err != nil {
+			fmt.Println("failed to emit", name, err)
+		}
+	})
+}
+
+// set queues a new value of a signal, unlock sets it.
+func set[T any](m *Manager, signal *crafter.Signal[T], value T) {
+	m.outbox = append(m.outbox, func() { signal.Set(value) })
+}
+
+// unlock releases mu, then sends what the outbox holds in order. The calls
+// back into the manager queue behind, a single caller sends at a time so the
+// page never sees an older value after a newer one.
+func (m *Manager) unlock() {
+	if m.flushing {
+		m.mu.Unlock()
+		return
+	}
+	m.flushing = true
+	for len(m.outbox) > 0 {
+		outbox := m.outbox
+		m.outbox = nil
+		m.mu.Unlock()
+		for _, send := range outbox {
+			send()
+		}
+		m.mu.Lock()
+	}
+	m.flushing = false
+	m.mu.Unlock()
+}
+
+// fetchDeck downloads the cards of the deck.
+func (m *Manager) fetchDeck(ctx context.Context) (internalfsrs.Cards, error) {
+	return crafterhttp.GetJSON[internalfsrs.Cards](ctx, m.client, DeckURL)
+}
+
+// syncDeck appends the cards added to the deck since the local state was
+// created, the progress of known cards is kept.
+func (m *Manager) syncDeck() {
+	incoming, err := m.fetchDeck(context.Background())
+	if err != nil {
+		fmt.Println("failed to fetch deck:", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.unlock()
+	merged, added := internalfsrs.Merge(m.cards, incoming)
+	if added == 0 {
+		return
+	}
+	m.cards = merged
+	for card := range slices.Values(m.cards) {
+		m.cardsLookup[card.ID] = card
+	}
+	if err := m.handleSaveState(); err != nil {
+		fmt.Println("failed to save state:", err)
+		return
+	}
+	fmt.Println("added", added, "new cards from deck")
+	m.emit("cards:loaded", cardsLoaded{Count: len(m.cards), Added: added})
+}
+
+func (m *Manager) sessionFromRecord(record *session.Record) *session.Session {
+	cards := internalfsrs.Cards{}
+	for _, cardID := range record.Cards {
+		cards = append(cards, m.cardsLookup[cardID])
+	}
+	return session.NewSession(cards)
+}
+
+// newSession based on the list of most urgent due date cards
+// prepare the list of cards.
+func (m *Manager) newSession() *session.Session {
+	revieweds := internalfsrs.Cards{}
+	news := internalfsrs.Cards{}
+	for _, card := range m.cards {
+		if card.Suspended {
+			continue
+		}
+		if card.Due.IsZero() {
+			news = append(news, card)
+		} else {
+			revieweds = append(revieweds, card)
+		}
+	}
+	sort.Sort(revieweds)
+	sort.Sort(news)
+	numCards := min(m.targetNum, len(revieweds)+len(news))
+	cards := make(internalfsrs.Cards, numCards)
+
+	// a fifth of reviewed cards, either kind makes up for the lack of the
+	// other.
+	numReviewed := min(int(0.2*float64(numCards)), len(revieweds))
+	numNews := min(numCards-numReviewed, len(news))
+	numReviewed = numCards - numNews
+
+	i := 0
+	for idx := 0; idx < numReviewed; idx, i = idx+1, i+1 {
+		cards[i] = revieweds[idx]
+	}
+
+	for idx := 0; idx < numNews; idx, i = idx+1, i+1 {
+		cards[i] = news[idx]
+	}
+
+	rand.Shuffle(numCards, func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+
+	return session.NewSession(cards)
+}
+
+func (m *Manager) addRecord(record session.Record) error {
+	id := len(m.records)
+
+	ptr := &record
+	ptr.ID = id
+	m.records = append(m.records, ptr)
+	return nil
+}
+
+func (m *Manager) completeSession() *session.Record {
+	record := session.NewRecordFromSession(m.currSession)
+	m.addRecord(record)
+	m.currSession = nil
+	m.undo = nil
+	storage := m.platform.Storage()
+	crafter.StorageSetItem(storage, "records", m.records)
+	crafter.StorageSetItem(storage, "flashcards", m.cards)
+	storage.RemoveItem("currentSession")
+	return m.records[len(m.records)-1]
+}
+
+// parsedFromLocalState pull the state passed from web browser.
+func (m *Manager) parsedFromLocalState() error {
+	if err := crafter.StorageGetItem(m.platform.Storage(), "flashcards", &m.cards); err != nil {
+		return fmt.Errorf("failed to pull flashcards, err: %v", err)
+	}
+	if err := crafter.StorageGetItem(m.platform.Storage(), "records", &m.records); err != nil {
+		return fmt.Errorf("failed to pull sessions, err: %v", err)
+	}
+
+	return nil
+}
+
+// handleSaveState push the state from wasm land to js land
+func (m *Manager) handleSaveState() error {
+	if err := crafter.StorageSetItem(m.platform.Storage(), "flashcards", &m.cards); err != nil {
+		return fmt.Errorf("failed to push flashcards, err: %v", err)
+	}
+	if err := crafter.StorageSetItem(m.platform.Storage(), "records", &m.records); err != nil {
+		return fmt.Errorf("failed to push sessions, err: %v", err)
+	}
+
+	if err := crafter.StorageSetItem(m.platform.Storage(), "currentSession", &m.currSession); err != nil {
+		return fmt.Errorf("failed to save current session, err: %v", err)
+	}
+	return nil
+}
+
+// Events emitted to the page.
+type (
+	// cardsLoaded follows the first load of the deck and every sync adding
+	// cards to it.
+	cardsLoaded struct {
+		Count  int  `json:"count"`
+		Seeded bool `json:"seeded,omitempty"`
+		Added  int  `json:"added,omitempty"`
+	}
+	// sessionCompleted follows the last card of a session, the record is
+	// saved already.
+	sessionCompleted struct {
+		RecordID int `json:"recordID"`
+		Cards    int `json:"cards"`
+	}
+	cardUndone struct {
+		CardID int `json:"cardID"`
+	}
+	cardRated struct {
+		CardID   int           `json:"cardID"`
+		Rating   fsrs.Rating   `json:"rating"`
+		Due      time.Time     `json:"due"`
+		Duration time.Duration `json:"duration"`
+	}
+)
+
+// NextResponse is either the card to review next or the end of the session.
+type NextResponse struct {
+	Card *model.Card `json:"card,omitempty"`
+	Stop bool        `json:"stop,omitempty"`
+}
+
+func (m *Manager) Next(struct{}) (NextResponse, error) {
+	m.mu.Lock()
+	defer m.unlock()
+	if len(m.cards) == 0 {
+		return NextResponse{}, crafter.Errorf(crafter.CodeNotReady, "no cards found")
+	}
+	if m.currSession == nil {
+		return NextResponse{}, crafter.Errorf(crafter.CodeNotReady, "not start session yet")
+	}
+
+	if m.currSession.ShouldStop() {
+		record := m.completeSession()
+		m.setCard(nil)
+		m.resetSuggestion()
+		m.publish()
+		m.emit("session:completed", sessionCompleted{RecordID: record.ID, Cards: len(record.Cards)})
+		return NextResponse{Stop: true}, nil
+	}
+
+	sort.Sort(m.currSession.Cards)
+	card := m.currSession.Cards[0]
+	m.show(card)
+	return NextResponse{Card: card}, nil
+}
+
+// show makes card the current one and starts its answer time, unless it is
+// shown already. Hard is suggested once the answer is slow.
+func (m *Manager) show(card *model.Card) {
+	if m.currSession.Shown == nil {
+		m.currSession.Shown = map[int]time.Time{}
+	}
+	shownAt, ok := m.currSession.Shown[card.ID]
+	if !ok {
+		shownAt = m.Now()
+		m.currSession.Shown[card.ID] = shownAt
+	}
+	m.setCard(card)
+	m.resetSuggestion()
+	if m.SlowAnswer <= 0 {
+		return
+	}
+	wait := max(m.SlowAnswer-m.Now().Sub(shownAt), 0)
+	// the timer fires on its own goroutine, once it holds the lock it is
+	// still m.slow unless the card was answered or left in between.
+	var slow *time.Timer
+	slow = time.AfterFunc(wait, func() {
+		m.mu.Lock()
+		defer m.unlock()
+		if m.slow == slow {
+			set(m, m.suggested, fsrs.Hard)
+		}
+	})
+	m.slow = slow
+}
+
+// setCard puts card on screen, nil for none.
+func (m *Manager) setCard(card *model.Card) {
+	m.card = card
+	set(m, m.current, card)
+}
+
+// resetSuggestion takes back the suggested rating, and stops waiting for a
+// slow answer.
+func (m *Manager) resetSuggestion() {
+	if m.slow != nil {
+		m.slow.Stop()
+		m.slow = nil
+	}
+	set(m, m.suggested, 0)
+}
+
+// publish updates the signals of the session for the bound elements.
+func (m *Manager) publish() {
+	if m.currSession == nil {
+		set(m, m.size, 0)
+		set(m, m.remaining, 0)
+		return
+	}
+	set(m, m.size, len(m.currSession.Cards))
+	set(m, m.remaining, m.currSession.Remaining())
+}
+
+type SubmitRequest struct {
+	CardID int
+	Rating fsrs.Rating
+}
+
+func (m *Manager) Submit(req SubmitRequest) (string, error) {
+	m.mu.Lock()
+	defer m.unlock()
+	if m.currSession == nil {
+		return "", crafter.Errorf(crafter.CodeNotReady, "not start session yet")
+	}
+	if req.Rating != 0 && (req.Rating < fsrs.Again || req.Rating > fsrs.Easy) {
+		return "", crafter.Errorf(crafter.CodeInvalidArgs, "invalid rating %d", req.Rating)
+	}
+	// the session stops once every one of its cards is looked at, so only
+	// its cards count.
+	idx := slices.IndexFunc(m.currSession.Cards, func(card *model.Card) bool { return card.ID == req.CardID })
+	if idx < 0 {
+		return "", crafter.Errorf(crafter.CodeNotFound, "submit for not exists card %d", req.CardID)
+	}
+	card := m.currSession.Cards[idx]
+	shownAt, shown := m.currSession.Shown[req.CardID]
+	m.undo = &undo{
+		card:    card,
+		before:  *card,
+		looked:  m.currSession.Looked[req.CardID],
+		again:   m.currSession.AgainsID[req.CardID],
+		shownAt: shownAt,
+		shown:   shown,
+		reviews: len(m.currSession.Reviews),
+	}
+	m.currSession.Looked[req.CardID] = true
+	defer m.publish()
+	if req.Rating == 0 {
+		return "not updated", nil
+	}
+
+	if req.Rating == fsrs.Again {
+		m.currSession.AgainsID[req.CardID] = true
+	} else {
+		delete(m.currSession.AgainsID, req.CardID)
+	}
+	// the answer took from when next showed the card, a card rated without
+	// being shown is not timed.
+	ratedAt := m.Now()
+	review := model.Review{CardID: card.ID, Rate: req.Rating, RatedAt: ratedAt}
+	if shown {
+		review.ShownAt = shownAt
+		review.Duration = ratedAt.Sub(shownAt)
+	}
+	m.currSession.Reviews = append(m.currSession.Reviews, review)
+	delete(m.currSession.Shown, req.CardID)
+	m.resetSuggestion()
+
+	fmt.Println("handle submit for", "id", req.CardID, card)
+	state := m.fsrs.Repeat(card.ToFsrsCard(), ratedAt)
+	card.SyncFromFSRSCard(state[req.Rating].Card)
+	m.emit("card:rated", cardRated{CardID: card.ID, Rating: req.Rating, Due: card.Due, Duration: review.Duration})
+	return "updated", nil
+}
+
+type undo struct {
+	card    *model.Card
+	before  model.Card
+	looked  bool
+	again   bool
+	shownAt time.Time
+	shown   bool
+	// reviews is the length of the review log before the submit.
+	reviews int
+}
+
+// Undo takes back the last submit of the session.
+func (m *Manager) Undo(struct{}) (string, error) {
+	m.mu.Lock()
+	defer m.unlock()
+	if m.currSession == nil {
+		return "", crafter.Errorf(crafter.CodeNotReady, "not start session yet")
+	}
+	if m.undo == nil {
+		return "", crafter.Errorf(crafter.CodeNotFound, "nothing to undo")
+	}
+	u := m.undo
+	m.undo = nil
+	// the card on screen goes back to the queue, its answer time starts over
+	// once it is shown again.
+	if m.card != nil && m.card != u.card {
+		delete(m.currSession.Shown, m.card.ID)
+	}
+	m.resetSuggestion()
+	*u.card = u.before
+	setOrDelete(m.currSession.Looked, u.card.ID, u.looked)
+	setOrDelete(m.currSession.AgainsID, u.card.ID, u.again)
+	if u.shown {
+		m.currSession.Shown[u.card.ID] = u.shownAt
+	}
+	m.currSession.Reviews = m.currSession.Reviews[:u.reviews]
+	m.publish()
+	m.emit("card:undone", cardUndone{CardID: u.card.ID})
+	return "undone", nil
+}
+
+func setOrDelete(set map[int]bool, id int, ok bool) {
+	if ok {
+		set[id] = true
+	} else {
+		delete(set, id)
+	}
+}
+
+type SuspendRequest struct {
+	CardID int
+}
+
+// Suspend leaves a card of the session out of this session and the next
+// ones.
+func (m *Manager) Suspend(req SuspendRequest) (string, error) {
+	m.mu.Lock()
+	defer m.unlock()
+	if m.currSession == nil {
+		return "", crafter.Errorf(crafter.CodeNotReady, "not start session yet")
+	}
+	idx := slices.IndexFunc(m.currSession.Cards, func(card *model.Card) bool { return card.ID == req.CardID })
+	if idx < 0 {
+		return "", crafter.Errorf(crafter.CodeNotFound, "suspend for not exists card %d", req.CardID)
+	}
+	card := m.currSession.Cards[idx]
+	card.Suspended = true
+	m.currSession.Cards = slices.Delete(m.currSession.Cards, idx, idx+1)
+	delete(m.currSession.Looked, card.ID)
+	delete(m.currSession.AgainsID, card.ID)
+	delete(m.currSession.Shown, card.ID)
+	if m.undo != nil && m.undo.card == card {
+		m.undo = nil
+	}
+	m.publish()
+	if err := m.handleSaveState(); err != nil {
+		return "", err
+	}
+	return "suspended", nil
+}
+
+// Start check if current URL is targeted specific session, then restore
+// the session from that id, otherwise, resume the session left for another
+// page or create a new session.
+func (m *Manager) Start(struct{}) (string, error) {
+	m.mu.Lock()
+	defer m.unlock()
+	// the elements bound to the session follow the one started.
+	previous := m.currSession
+	defer func() {
+		if m.currSession != previous {
+			m.setCard(nil)
+		}
+		m.publish()
+	}()
+
+	path := m.platform.Location().Href()
+	fmt.Println("path", path)
+
+	u, err := url.Parse(path)
+	if err != nil {
+		fmt.Println("failed to parse url", err)
+		m.currSession = m.newSession()
+		return "ready", nil
+	}
+
+	sessionID := u.Query().Get("id")
+	if sessionID == "" && m.currSession != nil {
+		return "resumed", nil
+	}
+	if sessionID == "" {
+		m.currSession = m.newSession()
+		return "ready", nil
+	}
+	fmt.Println("restart from sessionID", sessionID)
+
+	id, err := strconv.Atoi(sessionID)
+	record, exists := m.recordsLookup[id]
+	if err != nil || !exists {
+		m.currSession = m.newSession()
+		return "ready", nil
+	}
+	fmt.Println("restart from session", id)
+
+	m.currSession = m.sessionFromRecord(record)
+	return "ready", nil
+}
+
+// fetchSound downloads the pronunciation of word from the sound proxy as
+// raw audio/mpeg bytes.
+func (m *Manager) fetchSound(ctx context.Context, word, ipa, region string) ([]byte, error) {
+	headers := http.Header{}
+	headers.Set("Accept", "audio/mpeg")
+	resp, err := m.client.Get(ctx, api.Request{
+		Word:   word,
+		IPA:    ipa,
+		Region: region,
+	}.URL(), headers)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.Err(); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// soundCall is a download of a sound, shared by the callers asking for the
+// same one meanwhile.
+type soundCall struct {
+	done  chan struct{}
+	sound []byte
+	err   error
+}
+
+// soundFor returns the pronunciation of card in region, it is downloaded on
+// first use and then served from the audio store.
+func (m *Manager) soundFor(ctx context.Context, card *model.Card, region string) ([]byte, error) {
+	m.mu.Lock()
+	key, exists := card.Audio[region]
+	m.mu.Unlock()
+	if exists {
+		sound, err := m.audio.Get(key)
+		if err == nil {
+			return sound, nil
+		}
+		// the store entry is gone, fetch it again.
+		fmt.Println("cached sound is not available", err)
+	}
+
+	id := strconv.Itoa(card.ID) + ":" + region
+	m.mu.Lock()
+	call, exists := m.sounds[id]
+	if !exists {
+		call = &soundCall{done: make(chan struct{})}
+		m.sounds[id] = call
+		// the download outlives the caller giving up, the others still wait
+		// for it.
+		go func() {
+			call.sound, call.err = m.downloadSound(context.WithoutCancel(ctx), card, region)
+			m.mu.Lock()
+			delete(m.sounds, id)
+			m.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.sound, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// downloadSound fetches the pronunciation of card in region and keeps it in
+// the audio store.
+func (m *Manager) downloadSound(ctx context.Context, card *model.Card, region string) ([]byte, error) {
+	m.mu.Lock()
+	word, ipa := card.Word, card.IPA
+	m.mu.Unlock()
+	sound, err := m.fetchSound(ctx, word, ipa, region)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := m.audio.Put(sound)
+	if err != nil {
+		// still playable, just not cached.
+		fmt.Println("failed to cache sound", err)
+		return sound, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if card.Audio == nil {
+		card.Audio = map[string]string{}
+	}
+	card.Audio[region] = key
+	if err := m.handleSaveState(); err != nil {
+		fmt.Println("failed to save state", err)
+	}
+	return sound, nil
+}
+
+// Sound returns the pronunciation of a card in the default region.
+func (m *Manager) Sound(ctx context.Context, cardID int) ([]byte, error) {
+	m.mu.Lock()
+	card, exists := m.cardsLookup[cardID]
+	m.mu.Unlock()
+	if !exists {
+		return nil, crafter.Errorf(crafter.CodeNotFound, "play for not exists card %d", cardID)
+	}
+	return m.soundFor(ctx, card, DefaultRegion)
+}
+
+// Prefetch warms the audio store for every card of the current session in
+// the background, so playing them later does not wait for the network.
+func (m *Manager) Prefetch(struct{}) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.currSession == nil {
+		return "", crafter.Errorf(crafter.CodeNotReady, "not start session yet")
+	}
+
+	cards := slices.Clone(m.currSession.Cards)
+	go func() {
+		for _, card := range cards {
+			if _, err := m.soundFor(context.Background(), card, DefaultRegion); err != nil {
+				fmt.Println("failed to prefetch sound for", card.Word, err)
+			}
+		}
+		fmt.Println("prefetched sounds for", len(cards), "cards")
+	}()
+	return "prefetching", nil
+}
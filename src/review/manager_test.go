@@ -0,0 +1,502 @@
+package review_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/core/session"
+	"github.com/hnimtadd/spaced/src/crafter"
+	crafterhttp "github.com/hnimtadd/spaced/src/crafter/http"
+	"github.com/hnimtadd/spaced/src/pronunciation/api"
+	"github.com/hnimtadd/spaced/src/review"
+)
+
+// fixture runs the app on a fake page serving a deck of 12 cards, answers
+// take as long as clock is moved.
+type fixture struct {
+	app       *review.App
+	page      *crafter.Fake
+	transport *crafterhttp.Fake
+	client    *crafterhttp.Client
+	bus       *crafter.Events
+	clock     time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	deck := []model.Card{}
+	for i := range 12 {
+		deck = append(deck, model.Card{Word: fmt.Sprintf("word%d", i), IPA: "/w/", Definition: "d", Example: "e"})
+	}
+	deckJSON, err := json.Marshal(deck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{transport: crafterhttp.NewFake(), clock: time.Now()}
+	f.transport.Handle(http.MethodGet, review.DeckURL, http.StatusOK, deckJSON)
+	f.client = &crafterhttp.Client{Transport: f.transport}
+	f.page = crafter.NewFake("https://spaced.test/session")
+	f.bus = crafter.NewEvents(f.page.Dispatch)
+	f.app = review.NewApp(f.page, f.bus, f.client)
+	f.app.Manager.Now = func() time.Time { return f.clock }
+	// Hard is suggested after the short wait of a real timer.
+	f.app.Manager.SlowAnswer = 20 * time.Millisecond
+	return f
+}
+
+// start loads the deck and starts a session.
+func (f *fixture) start(t *testing.T) {
+	t.Helper()
+	m := f.app.Manager
+	if got := callAsync(t, m.Load); got != `{"success":true,"payload":"loaded"}` {
+		t.Fatalf("load: got %s", got)
+	}
+	if got := call(t, m.Start); got != `{"success":true,"payload":"ready"}` {
+		t.Fatalf("start: got %s", got)
+	}
+}
+
+// next returns the next card of the session, nil once it stops.
+func (f *fixture) next(t *testing.T) *model.Card {
+	t.Helper()
+	next, err := f.app.Manager.Next(struct{}{})
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	return next.Card
+}
+
+// stored returns the value of the store under key, or of its field.
+func (f *fixture) stored(t *testing.T, key string, field ...string) string {
+	t.Helper()
+	value, ok := f.app.Store.Value(key)
+	if !ok {
+		t.Fatalf("nothing stored under %s", key)
+	}
+	if len(field) > 0 {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(value, &fields); err != nil {
+			t.Fatalf("%s is not an object: %s", key, value)
+		}
+		value = fields[field[0]]
+	}
+	return string(value)
+}
+
+// events returns the events name dispatched to the page.
+func (f *fixture) events(name string) []string {
+	events := []string{}
+	for _, e := range f.page.Events() {
+		if e.Name == name {
+			events = append(events, string(e.Detail))
+		}
+	}
+	return events
+}
+
+// reviewAll rates every card of the session as easy until it stops, the
+// answer of the first one is slow. It returns the cards reviewed.
+func (f *fixture) reviewAll(t *testing.T) []int {
+	t.Helper()
+	reviewed := []int{}
+	for range 100 {
+		card := f.next(t)
+		if card == nil {
+			return reviewed
+		}
+		answer := 2 * time.Second
+		if len(reviewed) == 0 {
+			answer = 20 * time.Second
+		}
+		reviewed = append(reviewed, card.ID)
+		f.clock = f.clock.Add(answer)
+		// ids arrive as strings from craft-input.
+		if got := call(t, f.app.Manager.Submit, fmt.Sprintf(`"%d"`, card.ID), `4`); got != `{"success":true,"payload":"updated"}` {
+			t.Fatalf("submit: got %s", got)
+		}
+	}
+	t.Fatal("the session does not stop")
+	return nil
+}
+
+// call invokes fn with JSON arguments, the way main.js does, and returns
+// the JSON of its envelope.
+func call[Req, Resp any](t *testing.T, fn func(Req) (Resp, error), args ...string) string {
+	t.Helper()
+	data, err := json.Marshal(crafter.Invoke(fn, raw(args)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func raw(args []string) []json.RawMessage {
+	raw := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		raw[i] = json.RawMessage(arg)
+	}
+	return raw
+}
+
+func callAsync[Req, Resp any](t *testing.T, fn func(context.Context, Req) (Resp, error), args ...string) string {
+	t.Helper()
+	return call(t, func(req Req) (Resp, error) { return fn(context.Background(), req) }, args...)
+}
+
+func TestLoad(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	if got := call(t, m.Next); got != `{"success":false,"error":"no cards found","code":"not_ready"}` {
+		t.Errorf("next before load: got %s", got)
+	}
+	// first visit, the storage is seeded from the deck.
+	if got := callAsync(t, m.Load); got != `{"success":true,"payload":"loaded"}` {
+		t.Fatalf("load: got %s", got)
+	}
+	if got := f.events("cards:loaded"); !slices.Equal(got, []string{`{"count":12,"seeded":true}`}) {
+		t.Errorf("cards loaded: got %v", got)
+	}
+	cards := []model.Card{}
+	if err := crafter.StorageGetItem(f.page.Storage(), "flashcards", &cards); err != nil || len(cards) != 12 {
+		t.Errorf("expected 12 cards stored, got %d %v", len(cards), err)
+	}
+	if got := callAsync(t, m.Load); got != `{"success":true,"payload":"loaded"}` {
+		t.Errorf("load again: got %s", got)
+	}
+	if n := len(f.transport.Requests()); n != 1 {
+		t.Errorf("expected the deck fetched once, got %d requests", n)
+	}
+}
+
+// TestLoadSync merges the cards added to the deck since the last visit in
+// the background, while the handlers run.
+func TestLoadSync(t *testing.T) {
+	f := newFixture(t)
+	known := []model.Card{{Word: "word0", Reps: 3}, {ID: 1, Word: "old"}}
+	if err := crafter.StorageSetItem(f.page.Storage(), "flashcards", known); err != nil {
+		t.Fatal(err)
+	}
+	if err := crafter.StorageSetItem(f.page.Storage(), "records", []session.Record{}); err != nil {
+		t.Fatal(err)
+	}
+	m := f.app.Manager
+	if got := callAsync(t, m.Load); got != `{"success":true,"payload":"restored"}` {
+		t.Fatalf("load: got %s", got)
+	}
+	call(t, m.Start)
+	for deadline := time.Now().Add(time.Second); len(f.events("cards:loaded")) < 2 && time.Now().Before(deadline); {
+		f.next(t)
+		time.Sleep(time.Millisecond)
+	}
+	if got := f.events("cards:loaded"); !slices.Equal(got, []string{`{"count":2}`, `{"count":13,"added":11}`}) {
+		t.Fatalf("cards loaded: got %v", got)
+	}
+	cards := m.Cards()
+	if len(cards) != 13 || cards[0].Reps != 3 || cards[12].ID != 12 || cards[12].Word != "word11" {
+		t.Errorf("expected the new cards appended, got %d cards", len(cards))
+	}
+}
+
+func TestStart(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	callAsync(t, m.Load)
+	if got := call(t, m.Next); got != `{"success":false,"error":"not start session yet","code":"not_ready"}` {
+		t.Errorf("next before start: got %s", got)
+	}
+	if got := call(t, m.Start, `1`); got != `{"success":false,"error":"expected 0 arguments, got 1","code":"invalid_arguments"}` {
+		t.Errorf("start with argument: got %s", got)
+	}
+	if got := call(t, m.Start); got != `{"success":true,"payload":"ready"}` {
+		t.Errorf("start: got %s", got)
+	}
+	if got := call(t, m.Start); got != `{"success":true,"payload":"resumed"}` {
+		t.Errorf("start again: got %s", got)
+	}
+	if got := f.stored(t, "remaining"); got != `10` {
+		t.Errorf("expected 10 cards remaining, got %s", got)
+	}
+	if got := f.stored(t, "card"); got != `null` {
+		t.Errorf("expected no card yet, got %s", got)
+	}
+}
+
+func TestSubmitInvalid(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	if got := call(t, m.Submit, `0`, `3`); got != `{"success":false,"error":"not start session yet","code":"not_ready"}` {
+		t.Errorf("submit before start: got %s", got)
+	}
+	f.start(t)
+	tcs := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"without rating", []string{`0`}, `{"success":false,"error":"expected 2 arguments, got 1","code":"invalid_arguments"}`},
+		{"invalid rating", []string{`0`, `9`}, `{"success":false,"error":"invalid rating 9","code":"invalid_arguments"}`},
+		{"unknown card", []string{`99`, `3`}, `{"success":false,"error":"submit for not exists card 99","code":"not_found"}`},
+	}
+	for _, tc := range tcs {
+		if got := call(t, m.Submit, tc.args...); got != tc.want {
+			t.Errorf("%s: expected %s\n\tgot      %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	f.start(t)
+	if got := call(t, m.Undo); got != `{"success":false,"error":"nothing to undo","code":"not_found"}` {
+		t.Errorf("undo nothing: got %s", got)
+	}
+	card := f.next(t)
+	if got := call(t, m.Submit, strconv.Itoa(card.ID), `1`); got != `{"success":true,"payload":"updated"}` {
+		t.Fatalf("submit: got %s", got)
+	}
+	if card.Due.IsZero() {
+		t.Fatal("expected the card rated")
+	}
+	if got := call(t, m.Undo); got != `{"success":true,"payload":"undone"}` {
+		t.Fatalf("undo: got %s", got)
+	}
+	if !card.Due.IsZero() || card.Reps != 0 {
+		t.Errorf("expected the rating taken back, got %+v", card)
+	}
+	if got := f.stored(t, "remaining"); got != `10` {
+		t.Errorf("expected 10 cards remaining, got %s", got)
+	}
+	if got := call(t, m.Undo); got != `{"success":false,"error":"nothing to undo","code":"not_found"}` {
+		t.Errorf("undo twice: got %s", got)
+	}
+
+	// the card on screen when undoing is timed again once it is back.
+	if again := f.next(t); again.ID != card.ID {
+		t.Fatalf("expected the undone card next, got %d", again.ID)
+	}
+	call(t, m.Submit, strconv.Itoa(card.ID), `3`)
+	onScreen := f.next(t)
+	f.clock = f.clock.Add(time.Minute)
+	call(t, m.Undo)
+	for shown := f.next(t); shown.ID != onScreen.ID; shown = f.next(t) {
+		call(t, m.Submit, strconv.Itoa(shown.ID), `3`)
+	}
+	f.clock = f.clock.Add(time.Second)
+	call(t, m.Submit, strconv.Itoa(onScreen.ID), `3`)
+	if rated := f.events("card:rated"); !strings.HasSuffix(rated[len(rated)-1], `"duration":1000000000}`) {
+		t.Errorf("expected the answer time to restart, got %v", rated)
+	}
+}
+
+// TestReenter calls back into the manager from the subscribers of its
+// events and of the store, the way the listeners of the page may.
+func TestReenter(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	f.start(t)
+	calls := map[string]string{}
+	f.bus.On("store:changed", func(json.RawMessage) {
+		calls["cards"] = strconv.Itoa(len(m.Cards()))
+	})
+	f.bus.On("card:rated", func(json.RawMessage) {
+		if _, ok := calls["undo"]; !ok {
+			calls["undo"] = call(t, m.Undo)
+		}
+	})
+	f.bus.On("session:completed", func(json.RawMessage) {
+		calls["next"] = call(t, m.Next)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.reviewAll(t)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the manager deadlocked")
+	}
+	want := map[string]string{
+		"cards": "12",
+		"undo":  `{"success":true,"payload":"undone"}`,
+		"next":  `{"success":false,"error":"not start session yet","code":"not_ready"}`,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got %v, want %v", calls, want)
+	}
+}
+
+// TestSession reviews a whole session, a suspended card leaves it.
+func TestSession(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	f.start(t)
+
+	suspended := f.next(t)
+	id := strconv.Itoa(suspended.ID)
+	if got := call(t, m.Suspend, `"`+id+`"`); got != `{"success":true,"payload":"suspended"}` {
+		t.Fatalf("suspend: got %s", got)
+	}
+	if got := call(t, m.Suspend, id); got != `{"success":false,"error":"suspend for not exists card `+id+`","code":"not_found"}` {
+		t.Errorf("suspend twice: got %s", got)
+	}
+	if got := f.stored(t, "remaining"); got != `9` {
+		t.Errorf("expected 9 cards remaining, got %s", got)
+	}
+
+	// the first answer is slow.
+	card := f.next(t)
+	if got := f.stored(t, "card", "word"); got != fmt.Sprintf(`"word%d"`, card.ID) {
+		t.Errorf("expected the card stored, got %s", got)
+	}
+	if got := f.stored(t, "suggested"); got != `0` {
+		t.Errorf("expected no suggestion yet, got %s", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := f.stored(t, "suggested"); got != `2` {
+		t.Errorf("expected Hard suggested, got %s", got)
+	}
+	reviewed := f.reviewAll(t)
+	if len(reviewed) != 9 || reviewed[0] != card.ID || slices.Contains(reviewed, suspended.ID) {
+		t.Errorf("expected the 9 cards left reviewed, got %v", reviewed)
+	}
+
+	rated := f.events("card:rated")
+	if len(rated) != 9 || !strings.HasSuffix(rated[0], `"duration":20000000000}`) {
+		t.Errorf("expected the answer times rated, got %v", rated)
+	}
+	for key, want := range map[string]string{"remaining": `0`, "reviewed": `0`, "card": `null`, "suggested": `0`} {
+		if got := f.stored(t, key); got != want {
+			t.Errorf("expected %s stored under %s, got %s", want, key, got)
+		}
+	}
+	if got := f.events("session:completed"); !slices.Equal(got, []string{`{"recordID":0,"cards":9}`}) {
+		t.Errorf("session completed: got %v", got)
+	}
+	records := []session.Record{}
+	if err := crafter.StorageGetItem(f.page.Storage(), "records", &records); err != nil || len(records) != 1 {
+		t.Fatalf("expected the record stored, got %v %v", records, err)
+	}
+	reviews := records[0].Reviews
+	if len(reviews) != 9 || reviews[0].CardID != card.ID || reviews[0].Duration != 20*time.Second || reviews[1].Duration != 2*time.Second {
+		t.Errorf("unexpected review log %+v", reviews)
+	}
+	if _, ok := f.page.Storage().GetItem("currentSession"); ok {
+		t.Error("expected the current session cleared")
+	}
+}
+
+// TestReplay starts the session of a record on the next visit.
+func TestReplay(t *testing.T) {
+	f := newFixture(t)
+	f.start(t)
+	reviewed := f.reviewAll(t)
+
+	replay := crafter.NewFake("https://spaced.test/session?id=0")
+	for _, key := range []string{"flashcards", "records"} {
+		value, _ := f.page.Storage().GetItem(key)
+		_ = replay.Storage().SetItem(key, value)
+	}
+	m := review.NewManager(replay, crafter.NewEvents(replay.Dispatch), f.client)
+	if got := callAsync(t, m.Load); got != `{"success":true,"payload":"restored"}` {
+		t.Fatalf("load: got %s", got)
+	}
+	if got := call(t, m.Start); got != `{"success":true,"payload":"ready"}` {
+		t.Fatalf("start: got %s", got)
+	}
+	next, err := m.Next(struct{}{})
+	if err != nil || next.Card == nil || !slices.Contains(reviewed, next.Card.ID) {
+		t.Errorf("expected a card of the record, got %+v %v", next, err)
+	}
+}
+
+// TestSound downloads sounds once, then reads them from the audio store.
+func TestSound(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	f.start(t)
+	soundURL := api.Request{Word: "word0", IPA: "/w/", Region: review.DefaultRegion}.URL()
+	f.transport.Handle(http.MethodGet, soundURL, http.StatusOK, []byte("mp3"))
+	for range 2 {
+		if sound, err := m.Sound(context.Background(), 0); err != nil || string(sound) != "mp3" {
+			t.Fatalf("expected the sound, got %q %v", sound, err)
+		}
+	}
+	requests := 0
+	for _, req := range f.transport.Requests() {
+		if req.URL == soundURL {
+			requests++
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the sound fetched once, got %d requests", requests)
+	}
+	if _, err := m.Sound(context.Background(), 99); err == nil || !strings.Contains(err.Error(), "not_found") {
+		t.Errorf("sound of unknown card: got %v", err)
+	}
+}
+
+// gated holds the requests until release is closed.
+type gated struct {
+	crafterhttp.Transport
+	release chan struct{}
+}
+
+func (g gated) RoundTrip(ctx context.Context, req crafterhttp.Request) (*crafterhttp.Response, error) {
+	<-g.release
+	return g.Transport.RoundTrip(ctx, req)
+}
+
+// TestSoundShared downloads a sound once for the callers asking for it
+// meanwhile, a caller giving up leaves it to the others.
+func TestSoundShared(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	f.start(t)
+	soundURL := api.Request{Word: "word0", IPA: "/w/", Region: review.DefaultRegion}.URL()
+	f.transport.Handle(http.MethodGet, soundURL, http.StatusOK, []byte("mp3"))
+	release := make(chan struct{})
+	f.client.Transport = gated{Transport: f.transport, release: release}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	given := make(chan error)
+	go func() {
+		_, err := m.Sound(ctx, 0)
+		given <- err
+	}()
+	sounds := make(chan string, 3)
+	for range 3 {
+		go func() {
+			sound, err := m.Sound(context.Background(), 0)
+			if err != nil {
+				t.Error(err)
+			}
+			sounds <- string(sound)
+		}()
+	}
+	cancel()
+	if err := <-given; err != context.Canceled {
+		t.Errorf("expected the caller to give up, got %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	for range 3 {
+		if sound := <-sounds; sound != "mp3" {
+			t.Errorf("expected the sound, got %q", sound)
+		}
+	}
+	if n := len(f.transport.Requests()); n != 2 {
+		t.Errorf("expected the deck and the sound fetched once, got %d requests", n)
+	}
+}
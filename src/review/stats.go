@@ -0,0 +1,130 @@
+package review
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/core/session"
+	"github.com/hnimtadd/spaced/src/crafter"
+)
+
+// Stats serves the stats page from the records kept in the storage.
+type Stats struct {
+	// SlowAnswer is the average answer time from which a card is listed as
+	// slow.
+	SlowAnswer time.Duration
+
+	platform crafter.Platform
+	events   *crafter.Events
+}
+
+func NewStats(platform crafter.Platform, events *crafter.Events) *Stats {
+	return &Stats{SlowAnswer: DefaultSlowAnswer, platform: platform, events: events}
+}
+
+// maxSlowCards is how many slow cards the stats list.
+const maxSlowCards = 5
+
+// Render lists the completed sessions, the latest first.
+func (s *Stats) Render(struct{}) (string, error) {
+	records := []session.Record{}
+	if err := crafter.StorageGetItem(s.platform.Storage(), "records", &records); err != nil {
+		return "", crafter.Errorf(crafter.CodeNotReady, "failed to read from records: %v", err)
+	}
+	view := recordsView{Records: make([]recordView, len(records))}
+	reviews := []model.Review{}
+	for i, record := range records {
+		view.Records[i] = newRecordView(record)
+		reviews = append(reviews, record.Reviews...)
+	}
+	slices.Reverse(view.Records)
+	view.Answers = s.answers(reviews)
+	html, err := views.HTML(view)
+	if err != nil {
+		return "", err
+	}
+	return string(html), nil
+}
+
+// answers sums up the answer times of the timed reviews, the words of the
+// slow cards are read from the storage.
+func (s *Stats) answers(reviews []model.Review) answersView {
+	reviews = slices.DeleteFunc(slices.Clone(reviews), func(r model.Review) bool { return !r.Timed() })
+	view := answersView{Reviews: len(reviews)}
+	if len(reviews) == 0 {
+		return view
+	}
+	total := time.Duration(0)
+	byCard := map[int][]time.Duration{}
+	for _, review := range reviews {
+		total += review.Duration
+		byCard[review.CardID] = append(byCard[review.CardID], review.Duration)
+	}
+	view.Average = formatAnswer(total / time.Duration(len(reviews)))
+
+	cards := []model.Card{}
+	if err := crafter.StorageGetItem(s.platform.Storage(), "flashcards", &cards); err != nil {
+		fmt.Println("failed to read cards for stats:", err)
+	}
+	words := make(map[int]string, len(cards))
+	for _, card := range cards {
+		words[card.ID] = card.Word
+	}
+
+	slow := []slowCard{}
+	for id, durations := range byCard {
+		sum := time.Duration(0)
+		for _, d := range durations {
+			sum += d
+		}
+		average := sum / time.Duration(len(durations))
+		if average < s.SlowAnswer {
+			continue
+		}
+		word, ok := words[id]
+		if !ok {
+			word = "#" + strconv.Itoa(id)
+		}
+		slow = append(slow, slowCard{Word: word, Reviews: len(durations), average: average})
+	}
+	slices.SortFunc(slow, func(a, b slowCard) int {
+		return cmp.Or(cmp.Compare(b.average, a.average), strings.Compare(a.Word, b.Word))
+	})
+	slow = slow[:min(len(slow), maxSlowCards)]
+	for i := range slow {
+		slow[i].Average = formatAnswer(slow[i].average)
+	}
+	view.Slow = slow
+	return view
+}
+
+type ReplayRequest struct {
+	RecordID int
+}
+
+// sessionReplay is emitted to the page, which navigates to Path.
+type sessionReplay struct {
+	RecordID int    `json:"recordID"`
+	Path     string `json:"path"`
+}
+
+// Replay asks the page to start the session of a record again.
+func (s *Stats) Replay(req ReplayRequest) (string, error) {
+	records := []session.Record{}
+	if err := crafter.StorageGetItem(s.platform.Storage(), "records", &records); err != nil {
+		return "", crafter.Errorf(crafter.CodeNotReady, "failed to read from records: %v", err)
+	}
+	if req.RecordID < 0 || req.RecordID >= len(records) {
+		return "", crafter.Errorf(crafter.CodeNotFound, "invalid recordID %d", req.RecordID)
+	}
+	path := "/session?id=" + strconv.Itoa(req.RecordID)
+	if err := s.events.Emit("session:replay", sessionReplay{RecordID: req.RecordID, Path: path}); err != nil {
+		return "", err
+	}
+	return path, nil
+}
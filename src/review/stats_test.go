@@ -0,0 +1,75 @@
+package review_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+	"github.com/hnimtadd/spaced/src/review"
+)
+
+// TestStats lists the record of a session and replays it.
+func TestStats(t *testing.T) {
+	f := newFixture(t)
+	stats := f.app.Stats
+	if got := call(t, review.NewStats(crafter.NewFake("/stats"), crafter.NewEvents(nil)).Render); got != `{"success":false,"error":"failed to read from records: no item in storage: records","code":"not_ready"}` {
+		t.Errorf("stats without records: got %s", got)
+	}
+	f.start(t)
+	reviewed := f.reviewAll(t)
+
+	env := crafter.Invoke(stats.Render, nil)
+	html, _ := env.Payload.(string)
+	if !env.Success || !strings.Contains(html, `id="stat-0"`) || strings.Count(html, `craft-name="replay"`) != 1 {
+		t.Errorf("expected the record listed, got %+v", env)
+	}
+	// (20s + 9 × 2s) / 10 reviews.
+	if !strings.Contains(html, "3.8s over 10 reviews") {
+		t.Errorf("expected the average answer time, got %s", html)
+	}
+	if !strings.Contains(html, fmt.Sprintf("word%d</span>", reviewed[0])) || strings.Count(html, "20s over 1 review<") != 1 {
+		t.Errorf("expected the slow card, got %s", html)
+	}
+
+	tcs := []struct {
+		name string
+		arg  string
+		want string
+	}{
+		{"replay", `"0"`, `{"success":true,"payload":"/session?id=0"}`},
+		{"unknown record", `"3"`, `{"success":false,"error":"invalid recordID 3","code":"not_found"}`},
+		{"not a number", `"x"`, `{"success":false,"error":"argument 1 (RecordID): json: cannot unmarshal string into Go value of type int","code":"invalid_arguments"}`},
+	}
+	for _, tc := range tcs {
+		if got := call(t, stats.Replay, tc.arg); got != tc.want {
+			t.Errorf("%s: expected %s\n\tgot      %s", tc.name, tc.want, got)
+		}
+	}
+	if got := f.events("session:replay"); len(got) != 1 || got[0] != `{"recordID":0,"path":"/session?id=0"}` {
+		t.Errorf("session replay: got %v", got)
+	}
+}
+
+// TestStatsUntimed leaves the cards rated without being shown out of the
+// answer times.
+func TestStatsUntimed(t *testing.T) {
+	f := newFixture(t)
+	m := f.app.Manager
+	f.start(t)
+	card := f.next(t)
+	f.clock = f.clock.Add(4 * time.Second)
+	id := strconv.Itoa(card.ID)
+	call(t, m.Submit, id, `1`)
+	// rated again without next showing it.
+	call(t, m.Submit, id, `4`)
+	f.reviewAll(t)
+
+	html, _ := crafter.Invoke(f.app.Stats.Render, nil).Payload.(string)
+	// (4s + 20s + 8 × 2s) / 10 timed reviews.
+	if !strings.Contains(html, "4s over 10 reviews") {
+		t.Errorf("expected the untimed review left out, got %s", html)
+	}
+}
@@ -0,0 +1,98 @@
+package review
+
+import (
+	"embed"
+	"io/fs"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/core/model"
+	"github.com/hnimtadd/spaced/src/core/session"
+	"github.com/hnimtadd/spaced/src/crafter"
+	"github.com/hnimtadd/spaced/src/crafter/render"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// views renders the markup the handlers return to the pages.
+var views = func() *render.Set {
+	root, err := fs.Sub(templates, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return render.MustParse(root, nil)
+}()
+
+// Pages of the router.
+type (
+	homeView    struct{ Deck string }
+	sessionView struct{}
+	// statsPageView holds the records, the "stats" handler fills them in.
+	statsPageView struct{}
+	deckView      struct{ Cards []*model.Card }
+	settingsView  struct{ Shortcuts []crafter.Shortcut }
+	notFoundView  struct{}
+)
+
+func (homeView) View() string      { return "home" }
+func (sessionView) View() string   { return "session" }
+func (statsPageView) View() string { return "stats" }
+func (deckView) View() string      { return "deck" }
+func (settingsView) View() string  { return "settings" }
+func (notFoundView) View() string  { return "notfound" }
+
+// recordsView lists the completed sessions on the stats page.
+type recordsView struct {
+	Answers answersView
+	Records []recordView
+}
+
+// answersView sums up the answer times of every review.
+type answersView struct {
+	Reviews int
+	Average string
+	Slow    []slowCard
+}
+
+type slowCard struct {
+	Word    string
+	Reviews int
+	Average string
+	average time.Duration
+}
+
+// formatAnswer rounds an answer time to the tenth of second.
+func formatAnswer(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func (recordsView) View() string { return "records" }
+
+type recordView struct {
+	ID           int
+	CardReviewed int
+	Duration     string
+	Date         string
+	// AverageAnswer is empty for the records without timed reviews.
+	AverageAnswer string
+}
+
+func newRecordView(r session.Record) recordView {
+	view := recordView{
+		ID:           r.ID,
+		CardReviewed: len(r.Cards),
+		Duration:     r.CompletedAt.Sub(r.StartedAt).Round(time.Second).String(),
+		Date:         r.StartedAt.Format(time.DateTime),
+	}
+	total, timed := time.Duration(0), 0
+	for _, review := range r.Reviews {
+		if review.Timed() {
+			total += review.Duration
+			timed++
+		}
+	}
+	if timed > 0 {
+		view.AverageAnswer = formatAnswer(total / time.Duration(timed))
+	}
+	return view
+}
@@ -0,0 +1,204 @@
+// Package site resolves request paths to the files of the static ui the way
+// Vercel does: files first, then clean URLs, then the rewrites of vercel.json.
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NotFoundPage is served with a 404 status when nothing matches.
+const NotFoundPage = "404.html"
+
+// Rewrite serves Destination when a request path matches Source and no file
+// does. Source uses the path-to-regexp syntax of vercel.json: ":name" matches
+// a segment, ":name*" any number of them and "(...)" a regular expression,
+// Destination refers to them as ":name" and "$1".
+type Rewrite struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// ReadRewrites reads the rewrites of a vercel.json file.
+func ReadRewrites(data []byte) ([]Rewrite, error) {
+	config := struct {
+		Rewrites []Rewrite `json:"rewrites"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse rewrites: %w", err)
+	}
+	return config.Rewrites, nil
+}
+
+// Site is an fs.FS over the static files where Open resolves names like a
+// request path: "stats" opens stats.html, "assets" opens assets/index.html
+// and rewrites apply to missing files.
+type Site struct {
+	fsys     fs.FS
+	rewrites []rewrite
+}
+
+type rewrite struct {
+	Rewrite
+	source *regexp.Regexp
+}
+
+func New(fsys fs.FS, rewrites []Rewrite) (*Site, error) {
+	s := &Site{fsys: fsys}
+	for _, r := range rewrites {
+		source, err := compile(r.Source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rewrite source %q: %w", r.Source, err)
+		}
+		s.rewrites = append(s.rewrites, rewrite{Rewrite: r, source: source})
+	}
+	return s, nil
+}
+
+// Open opens the file Resolve picks for name.
+func (s *Site) Open(name string) (fs.File, error) {
+	resolved, err := s.Resolve(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return s.fsys.Open(resolved)
+}
+
+// Resolve returns the file serving the request path p, or fs.ErrNotExist.
+// The path is cleaned first, so ".." never leaves the root, and names fs.FS
+// rejects are reported as fs.ErrInvalid.
+func (s *Site) Resolve(p string) (string, error) {
+	if strings.ContainsAny(p, "\\\x00") {
+		return "", fs.ErrInvalid
+	}
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return "", fs.ErrInvalid
+	}
+
+	if resolved, ok := s.lookup(name); ok {
+		return resolved, nil
+	}
+	for _, r := range s.rewrites {
+		destination, ok := r.apply("/" + strings.TrimPrefix(name, "."))
+		if !ok {
+			continue
+		}
+		target := strings.TrimPrefix(path.Clean(destination), "/")
+		if target == "" {
+			target = "."
+		}
+		if !fs.ValidPath(target) {
+			return "", fs.ErrInvalid
+		}
+		// a rewrite onto itself, like /assets/:match*, keeps missing files
+		// missing instead of falling through to the catch all.
+		if resolved, ok := s.lookup(target); ok {
+			return resolved, nil
+		}
+		return "", fs.ErrNotExist
+	}
+	return "", fs.ErrNotExist
+}
+
+// lookup finds name as a file, a directory index or a clean URL.
+func (s *Site) lookup(name string) (string, bool) {
+	candidates := []string{name, path.Join(name, "index.html")}
+	if name != "." && path.Ext(name) != ".html" {
+		candidates = append(candidates, name+".html")
+	}
+	for _, candidate := range candidates {
+		info, err := fs.Stat(s.fsys, candidate)
+		if err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func (r rewrite) apply(p string) (string, bool) {
+	match := r.source.FindStringSubmatch(p)
+	if match == nil {
+		return "", false
+	}
+	destination := r.Destination
+	// the :name* form goes first, else its * would be left behind.
+	names := r.source.SubexpNames()
+	for i := len(match) - 1; i > 0; i-- {
+		if names[i] != "" {
+			destination = strings.ReplaceAll(destination, ":"+names[i]+"*", match[i])
+			destination = strings.ReplaceAll(destination, ":"+names[i], match[i])
+		}
+		destination = strings.ReplaceAll(destination, "$"+strconv.Itoa(i), match[i])
+	}
+	return destination, true
+}
+
+// compile turns a path-to-regexp source into an anchored regular expression.
+func compile(source string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(source); {
+		switch c := source[i]; {
+		case c == ':':
+			j := i + 1
+			for j < len(source) && isIdent(source[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("missing parameter name at %d", i)
+			}
+			name := source[i+1 : j]
+			modifier := byte(0)
+			if j < len(source) && strings.IndexByte("*+?", source[j]) >= 0 {
+				modifier = source[j]
+				j++
+			}
+			switch modifier {
+			case '*':
+				fmt.Fprintf(&b, "(?P<%s>.*)", name)
+			case '+':
+				fmt.Fprintf(&b, "(?P<%s>.+)", name)
+			case '?':
+				fmt.Fprintf(&b, "(?P<%s>[^/]*)", name)
+			default:
+				fmt.Fprintf(&b, "(?P<%s>[^/]+)", name)
+			}
+			i = j
+		case c == '(':
+			depth, j := 0, i
+			for ; j < len(source); j++ {
+				if source[j] == '(' {
+					depth++
+				} else if source[j] == ')' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if j == len(source) {
+				return nil, fmt.Errorf("unterminated group at %d", i)
+			}
+			b.WriteString(source[i : j+1])
+			i = j + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			i++
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,126 @@
+package site_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hnimtadd/spaced/src/site"
+)
+
+// newSite serves a ui with the rewrites of vercel.json, secret sits next to
+// its root so no path may reach it.
+func newSite(t *testing.T) *site.Site {
+	t.Helper()
+	data, err := os.ReadFile("../../vercel.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrites, err := site.ReadRewrites(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"secret":                "secret",
+		"ui/index.html":         "index",
+		"ui/reset.html":         "reset",
+		"ui/404.html":           "not found",
+		"ui/main.js":            "js",
+		"ui/assets/cards.json":  "[]",
+		"ui/assets/app.wasm":    "wasm",
+		"ui/assets/nested/a.js": "a",
+		"ui/docs/index.html":    "docs",
+		"ui/docs/guide.html":    "guide",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := site.New(os.DirFS(filepath.Join(dir, "ui")), rewrites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestResolve(t *testing.T) {
+	s := newSite(t)
+	tcs := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr error
+	}{
+		{name: "root", path: "/", want: "index.html"},
+		{name: "file", path: "/index.html", want: "index.html"},
+		{name: "script", path: "/main.js", want: "main.js"},
+		{name: "clean url", path: "/reset", want: "reset.html"},
+		{name: "nested clean url", path: "/docs/guide", want: "docs/guide.html"},
+		{name: "directory index", path: "/docs", want: "docs/index.html"},
+		{name: "directory index with slash", path: "/docs/", want: "docs/index.html"},
+		// assets are never rewritten to the app.
+		{name: "asset", path: "/assets/app.wasm", want: "assets/app.wasm"},
+		{name: "nested asset", path: "/assets/nested/a.js", want: "assets/nested/a.js"},
+		{name: "missing asset", path: "/assets/missing.wasm", wantErr: fs.ErrNotExist},
+		// the router of the module renders the other pages.
+		{name: "spa stats", path: "/stats", want: "index.html"},
+		{name: "spa session", path: "/session", want: "index.html"},
+		{name: "spa deck", path: "/decks/42", want: "index.html"},
+		{name: "spa unknown", path: "/unknown/page", want: "index.html"},
+		{name: "traversal", path: "/../secret", want: "index.html"},
+		{name: "traversal from assets", path: "/assets/../../secret", want: "index.html"},
+		{name: "relative traversal", path: "../../etc/passwd", want: "index.html"},
+		{name: "encoded traversal", path: "/assets/..%2f..%2fsecret", wantErr: fs.ErrNotExist},
+		{name: "backslash traversal", path: "/assets/..\\..\\secret", wantErr: fs.ErrInvalid},
+		{name: "nul byte", path: "/main.js\x00.html", wantErr: fs.ErrInvalid},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Resolve(tc.path)
+			switch {
+			case tc.wantErr != nil && !errors.Is(err, tc.wantErr):
+				t.Errorf("Resolve(%q): expected %v, got %q %v", tc.path, tc.wantErr, got, err)
+			case tc.wantErr == nil && (err != nil || got != tc.want):
+				t.Errorf("Resolve(%q): expected %q, got %q %v", tc.path, tc.want, got, err)
+			}
+		})
+	}
+}
+
+// TestFileServer sends raw traversal requests through http.FileServerFS over
+// the site, the way any fs.FS consumer would use it.
+func TestFileServer(t *testing.T) {
+	server := httptest.NewServer(http.FileServerFS(newSite(t)))
+	defer server.Close()
+
+	for _, p := range []string{"/../secret", "/%2e%2e/secret", "/assets/%2e%2e/%2e%2e/secret", "/..%5csecret"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// keep the path as is, the client would clean it.
+		req.URL.Opaque = p
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) == "secret" {
+			t.Errorf("%q escaped the root", p)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/metrics"
+)
+
+var (
+	ErrTimeout     = errors.New("upstream timed out")
+	ErrUnavailable = errors.New("upstream unavailable")
+	ErrNotFound    = errors.New("upstream resource not found")
+	ErrTooLarge    = errors.New("upstream response too large")
+	ErrCircuitOpen = errors.New("upstream circuit open")
+	ErrBusy        = errors.New("upstream busy")
+)
+
+var (
+	requestDuration = metrics.Default.Histogram("spaced_upstream_request_duration_seconds",
+		"Duration of the attempts to fetch from the upstream by outcome.", metrics.DefaultBuckets, "outcome")
+	inFlight = metrics.Default.Gauge("spaced_upstream_requests_in_flight",
+		"Upstream fetches holding a concurrency slot.")
+	rejected = metrics.Default.Counter("spaced_upstream_rejected_total",
+		"Fetches refused before reaching the upstream by reason.", "reason")
+)
+
+// Error is returned by Client for every failed fetch, Kind is one of the
+// Err* values above.
+type Error struct {
+	Kind   error
+	URL    string
+	Status int // status of the last upstream response, 0 if there was none
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%v: %s", e.Kind, e.URL)
+	}
+	return fmt.Sprintf("%v: %s: %v", e.Kind, e.URL, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Classify maps err to the status and error code an API should answer with.
+func Classify(err error) (int, string) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "canceled"
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, ErrTimeout):
+		return http.StatusGatewayTimeout, "upstream_timeout"
+	case errors.Is(err, ErrTooLarge):
+		return http.StatusBadGateway, "upstream_too_large"
+	case errors.Is(err, ErrCircuitOpen):
+		return http.StatusServiceUnavailable, "upstream_circuit_open"
+	case errors.Is(err, ErrBusy):
+		return http.StatusServiceUnavailable, "upstream_busy"
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusBadGateway, "upstream_unavailable"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
+
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Client fetches from a single upstream with per attempt deadlines, bounded
+// retries with exponential backoff, a circuit breaker and a body size limit.
+type Client struct {
+	HTTP *http.Client
+	// Header is sent with every request.
+	Header http.Header
+	// Timeout bounds a single attempt, the caller context bounds them all.
+	Timeout time.Duration
+	// Retries is the number of attempts after the first one, only network
+	// errors, 429 and 5xx are retried.
+	Retries int
+	// Backoff is the wait before the first retry, doubled on each retry.
+	Backoff     time.Duration
+	MaxBodySize int64
+
+	breaker *breaker
+	slots   *slots
+}
+
+// The load limits of the clients New returns, see SetConcurrency and
+// SetBreaker.
+const (
+	DefaultConcurrency      = 8
+	DefaultQueue            = 64
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+func New() *Client {
+	return &Client{
+		HTTP: &http.Client{},
+		Header: http.Header{
+			http.CanonicalHeaderKey("User-Agent"): []string{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+		},
+		Timeout:     10 * time.Second,
+		Retries:     2,
+		Backoff:     200 * time.Millisecond,
+		MaxBodySize: 5 << 20,
+		breaker:     newBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		slots:       newSlots(DefaultConcurrency, DefaultQueue),
+	}
+}
+
+// Default is shared by every handler talking to the dictionary.
+var Default = New()
+
+// Get fetches url, the response is only returned for 200 OK.
+func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
+	if !c.breaker.allow() {
+		rejected.Inc("circuit_open")
+		return nil, &Error{Kind: ErrCircuitOpen, URL: url}
+	}
+
+	var lastErr *Error
+	backoff := c.Backoff
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			// full jitter, so concurrent callers do not retry in lockstep.
+			wait := backoff/2 + rand.N(backoff/2+1)
+			select {
+			case <-ctx.Done():
+				kind := ErrUnavailable
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					kind = ErrTimeout
+				}
+				return nil, c.fail(&Error{Kind: kind, URL: url, Err: ctx.Err()})
+			case <-time.After(wait):
+			}
+			backoff *= 2
+		}
+
+		if err := c.slots.acquire(ctx); err != nil {
+			if errors.Is(err, ErrBusy) {
+				rejected.Inc("busy")
+			}
+			return nil, c.fail(&Error{Kind: err, URL: url, Err: ctx.Err()})
+		}
+		inFlight.Add(1)
+		start := time.Now()
+		resp, err := c.do(ctx, url)
+		requestDuration.Observe(time.Since(start).Seconds(), outcome(err))
+		inFlight.Add(-1)
+		c.slots.release()
+		if err == nil {
+			c.breaker.success()
+			return resp, nil
+		}
+		lastErr = err
+		if !retryable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, c.fail(lastErr)
+}
+
+// fail records err on the breaker, a missing resource means the upstream
+// is healthy, a canceled or queued out call tells nothing about it.
+func (c *Client) fail(err *Error) *Error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.breaker.success()
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrBusy):
+		c.breaker.release()
+	default:
+		c.breaker.failure()
+	}
+	return err
+}
+
+func (c *Client) do(ctx context.Context, url string) (*Response, *Error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, &Error{Kind: ErrUnavailable, URL: url, Err: err}
+	}
+	req.Header = c.Header.Clone()
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &Error{Kind: ErrTimeout, URL: url, Err: err}
+		}
+		return nil, &Error{Kind: ErrUnavailable, URL: url, Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		// drain a bit so the connection can be reused, never forward it.
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		kind := ErrUnavailable
+		if resp.StatusCode == http.StatusNotFound {
+			kind = ErrNotFound
+		}
+		return nil, &Error{Kind: kind, URL: url, Status: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.MaxBodySize+1))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &Error{Kind: ErrTimeout, URL: url, Status: resp.StatusCode, Err: err}
+		}
+		return nil, &Error{Kind: ErrUnavailable, URL: url, Status: resp.StatusCode, Err: err}
+	}
+	if int64(len(body)) > c.MaxBodySize {
+		return nil, &Error{Kind: ErrTooLarge, URL: url, Status: resp.StatusCode}
+	}
+	return &Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   body,
+	}, nil
+}
+
+// outcome labels the duration of an attempt.
+func outcome(err *Error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrTooLarge):
+		return "too_large"
+	case err.Status != 0:
+		return "status_" + strconv.Itoa(err.Status)
+	default:
+		return "error"
+	}
+}
+
+func retryable(err *Error) bool {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrTooLarge):
+		return false
+	case err.Status == 0:
+		return true
+	default:
+		return err.Status == http.StatusTooManyRequests || err.Status >= 500
+	}
+}
+
+// breaker opens after threshold consecutive failures and rejects calls for
+// cooldown, then lets a single trial call through to decide whether to close.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Since(b.openedAt) < b.cooldown || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// open reports whether calls are rejected right now, without taking the
+// trial call.
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold && (time.Since(b.openedAt) < b.cooldown || b.trial)
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+}
+
+// release gives up a trial call without a verdict.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
+
+// SetBreaker replaces the circuit breaker settings, it resets its state.
+func (c *Client) SetBreaker(threshold int, cooldown time.Duration) {
+	c.breaker = newBreaker(threshold, cooldown)
+}
+
+// CircuitOpen reports whether the breaker currently rejects calls to the
+// upstream.
+func (c *Client) CircuitOpen() bool {
+	return c.breaker.open()
+}
+
+// SetConcurrency caps the requests in flight to the upstream at max, at most
+// queue more callers wait for a slot, the others fail with ErrBusy.
+func (c *Client) SetConcurrency(max, queue int) {
+	c.slots = newSlots(max, queue)
+}
+
+// slots is a semaphore with a bounded waiting queue.
+type slots struct {
+	sem   chan struct{}
+	queue chan struct{}
+}
+
+func newSlots(max, queue int) *slots {
+	return &slots{
+		sem:   make(chan struct{}, max),
+		queue: make(chan struct{}, max+queue),
+	}
+}
+
+// acquire waits for a free slot, it returns ErrBusy when the queue is full
+// and ErrTimeout or ErrUnavailable when ctx ends first.
+func (s *slots) acquire(ctx context.Context) error {
+	select {
+	case s.queue <- struct{}{}:
+	default:
+		return ErrBusy
+	}
+	select {
+	case s.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		<-s.queue
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrTimeout
+		}
+		return ErrUnavailable
+	}
+}
+
+func (s *slots) release() {
+	<-s.sem
+	<-s.queue
+}
@@ -0,0 +1,249 @@
+package upstream_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hnimtadd/spaced/src/upstream"
+)
+
+// TestGet runs the client against local servers that are slow, flaky or
+// broken.
+func TestGet(t *testing.T) {
+	tcs := []struct {
+		name string
+		// handle answers the nth (0 based) request.
+		handle   func(n int32, w http.ResponseWriter)
+		ctx      func() (context.Context, context.CancelFunc)
+		backoff  time.Duration
+		wantErr  error
+		wantBody string
+		wantHits int32
+	}{
+		{
+			name:     "ok",
+			handle:   func(_ int32, w http.ResponseWriter) { fmt.Fprint(w, "sound") },
+			wantBody: "sound",
+			wantHits: 1,
+		},
+		{
+			name: "flaky then ok",
+			handle: func(n int32, w http.ResponseWriter) {
+				if n < 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprint(w, "sound")
+			},
+			wantBody: "sound",
+			wantHits: 3,
+		},
+		{
+			name: "rate limited then ok",
+			handle: func(n int32, w http.ResponseWriter) {
+				if n == 0 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				fmt.Fprint(w, "sound")
+			},
+			wantBody: "sound",
+			wantHits: 2,
+		},
+		{
+			name: "always failing",
+			handle: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, "<html>upstream stack trace</html>")
+			},
+			wantErr:  upstream.ErrUnavailable,
+			wantHits: 3,
+		},
+		{
+			name:     "not found is not retried",
+			handle:   func(_ int32, w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) },
+			wantErr:  upstream.ErrNotFound,
+			wantHits: 1,
+		},
+		{
+			name: "slow",
+			handle: func(_ int32, w http.ResponseWriter) {
+				time.Sleep(200 * time.Millisecond)
+				fmt.Fprint(w, "sound")
+			},
+			wantErr:  upstream.ErrTimeout,
+			wantHits: 3,
+		},
+		{
+			name:     "too large",
+			handle:   func(_ int32, w http.ResponseWriter) { fmt.Fprint(w, strings.Repeat("x", 2048)) },
+			wantErr:  upstream.ErrTooLarge,
+			wantHits: 1,
+		},
+		{
+			name: "caller gives up",
+			handle: func(_ int32, w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 5*time.Millisecond)
+			},
+			backoff:  50 * time.Millisecond,
+			wantErr:  upstream.ErrTimeout,
+			wantHits: 1,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				tc.handle(hits.Add(1)-1, w)
+			}))
+			defer server.Close()
+			client := newClient()
+			if tc.backoff > 0 {
+				client.Backoff = tc.backoff
+			}
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tc.ctx != nil {
+				ctx, cancel = tc.ctx()
+			}
+			defer cancel()
+			resp, err := client.Get(ctx, server.URL)
+
+			switch {
+			case tc.wantErr != nil && !errors.Is(err, tc.wantErr):
+				t.Errorf("expected %v, got %v", tc.wantErr, err)
+			case tc.wantErr == nil && (err != nil || string(resp.Body) != tc.wantBody):
+				t.Errorf("expected body %q, got %v %v", tc.wantBody, resp, err)
+			}
+			if hits.Load() != tc.wantHits {
+				t.Errorf("expected %d upstream hits, got %d", tc.wantHits, hits.Load())
+			}
+		})
+	}
+}
+
+func newClient() *upstream.Client {
+	client := upstream.New()
+	client.Timeout = 50 * time.Millisecond
+	client.Backoff = time.Millisecond
+	client.MaxBodySize = 1024
+	client.SetBreaker(100, time.Minute)
+	return client
+}
+
+// TestBreaker opens the circuit with a failing upstream, checks calls are
+// rejected without reaching it, then lets the trial call close it again.
+func TestBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "sound")
+	}))
+	defer server.Close()
+
+	client := newClient()
+	client.Retries = 0
+	client.SetBreaker(3, 50*time.Millisecond)
+
+	for range 3 {
+		_, _ = client.Get(context.Background(), server.URL)
+	}
+	if _, err := client.Get(context.Background(), server.URL); !errors.Is(err, upstream.ErrCircuitOpen) || hits.Load() != 3 {
+		t.Fatalf("expected open circuit after 3 hits, got %v after %d hits", err, hits.Load())
+	}
+	if !client.CircuitOpen() {
+		t.Fatal("expected CircuitOpen to report the open circuit")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	if _, err := client.Get(context.Background(), server.URL); err != nil {
+		t.Fatalf("expected trial call to succeed, got %v", err)
+	}
+	if _, err := client.Get(context.Background(), server.URL); err != nil || client.CircuitOpen() {
+		t.Fatalf("expected closed circuit, got %v", err)
+	}
+}
+
+// TestConcurrency floods a slow upstream, checks no more than the cap reach
+// it at once and that callers past the queue are turned away.
+func TestConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		fmt.Fprint(w, "sound")
+	}))
+	defer server.Close()
+
+	client := newClient()
+	client.Timeout = time.Second
+	client.SetConcurrency(2, 3)
+
+	var wg sync.WaitGroup
+	var ok, busy atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Get(context.Background(), server.URL)
+			switch {
+			case err == nil:
+				ok.Add(1)
+			case errors.Is(err, upstream.ErrBusy):
+				busy.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 || ok.Load() != 5 || busy.Load() != 3 {
+		t.Fatalf("expected peak <= 2, 5 ok and 3 busy, got peak %d, %d ok and %d busy", peak.Load(), ok.Load(), busy.Load())
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tcs := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{err: &upstream.Error{Kind: upstream.ErrNotFound}, status: http.StatusNotFound, code: "not_found"},
+		{err: &upstream.Error{Kind: upstream.ErrTimeout}, status: http.StatusGatewayTimeout, code: "upstream_timeout"},
+		{err: &upstream.Error{Kind: upstream.ErrTooLarge}, status: http.StatusBadGateway, code: "upstream_too_large"},
+		{err: &upstream.Error{Kind: upstream.ErrCircuitOpen}, status: http.StatusServiceUnavailable, code: "upstream_circuit_open"},
+		{err: &upstream.Error{Kind: upstream.ErrBusy}, status: http.StatusServiceUnavailable, code: "upstream_busy"},
+		{err: &upstream.Error{Kind: upstream.ErrUnavailable, Err: context.Canceled}, status: http.StatusServiceUnavailable, code: "canceled"},
+		{err: &upstream.Error{Kind: upstream.ErrUnavailable}, status: http.StatusBadGateway, code: "upstream_unavailable"},
+		{err: errors.New("boom"), status: http.StatusInternalServerError, code: "internal"},
+	}
+	for _, tc := range tcs {
+		if status, code := upstream.Classify(tc.err); status != tc.status || code != tc.code {
+			t.Errorf("classify %v: expected %d %s, got %d %s", tc.err, tc.status, tc.code, status, code)
+		}
+	}
+}
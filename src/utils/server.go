@@ -3,9 +3,17 @@ package utils
 import (
 	"encoding/json"
 	"io"
+	"net/http"
 )
 
 func SMarshal(w io.Writer, data any) error {
 	enc := json.NewEncoder(w)
 	return enc.Encode(data)
 }
+
+// SError writes the JSON error body shared by every API handler, code is a
+// stable machine readable identifier, message is for humans.
+func SError(w http.ResponseWriter, status int, code string, message string) {
+	w.WriteHeader(status)
+	SMarshal(w, map[string]any{"error": message, "code": code})
+}
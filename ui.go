@@ -0,0 +1,27 @@
+// Package spaced embeds the web ui, so cmd/server ships as a single binary.
+//
+// The WASM module is built into ui/assets by go generate, which also names
+// it and the scripts after their content and renders ui/index.html from
+// ui/index.html.tmpl. They must exist before cmd/server is built for them
+// to be embedded:
+//
+//	go generate . && go build ./cmd/server
+package spaced
+
+import "embed"
+
+//go:generate env GOOS=js GOARCH=wasm go build -o ui/assets/app.wasm ./wasm/app
+//go:generate sh -c "cp \"$(go env GOROOT)/lib/wasm/wasm_exec.js\" ui/wasm_exec.js"
+//go:generate go run ./cmd/assets ui
+
+// UI holds the files the ui serves, the site root is UI's "ui" sub
+// directory. The sources the generator reads, main.js, wasm_exec.js and
+// index.html.tmpl, are left out.
+//
+//go:embed ui/*.html ui/style.css ui/favicon.ico ui/assets
+var UI embed.FS
+
+// VercelJSON carries the rewrites the deployed site applies.
+//
+//go:embed vercel.json
+var VercelJSON []byte
@@ -1,4 +1,5 @@
-const WASM_URL = `/assets/${globalThis.location.pathname}.wasm`;
+// one module serves every page, its router renders them in place.
+const WASM_URL = "/assets/app.wasm";
 
 function parseCraftAddress(addr) {
   if (!addr) return null;
@@ -47,11 +48,42 @@ function parseCraftInput(input) {
   });
 }
 
+// parseCraftBind splits craft-bind="card.word:[title]" into the key of the
+// store, the path into its value and the property to set.
+function parseCraftBind(bind) {
+  const [path, prop] = bind.trim().split(":");
+  const [key, ...fields] = path.split(".");
+  return { key, fields, prop };
+}
+
+// unwrap returns the payload of a handler envelope, failures are logged and
+// yield undefined. Results which are not envelopes are returned as is.
+function unwrap(method, response) {
+  if (!response || typeof response !== "object" || !("success" in response)) {
+    return response;
+  }
+  if (!response.success) {
+    console.error(`crafter: '${method}' failed (${response.code}): ${response.error}`);
+    return undefined;
+  }
+  return response.payload;
+}
+
 class Crafter {
   constructor() {
     this.go = null;
     this.wasmBridge = null;
     this.isReady = null;
+    this.navigation = null;
+    // the page the router mounted last.
+    this.route = null;
+    // values of the store of the module, by key. It publishes them before
+    // init resolves, so the listener is set up front.
+    this.state = {};
+    globalThis.addEventListener("store:changed", (e) => {
+      this.state[e.detail.key] = e.detail.value;
+      this.applyBindings(e.detail.key);
+    });
   }
 
   async init() {
@@ -75,20 +107,152 @@ class Crafter {
       this.wasmBridge = globalThis.wasmBridge;
       this.isReady = true;
       console.info("Crafter: initialized");
-      this.call("init");
+      if (this.wasmBridge.init) {
+        await this.call("init");
+      }
     } catch (err) {
       console.error(`Crafter: Error loading Go WASM module: ${err}`);
     }
   }
   start() {
     this.buildIndex();
+    document.addEventListener("keydown", (e) => this.shortcut(e));
+    if (!this.wasmBridge?.navigate) return;
+
+    // links to the pages of the router are rendered in place.
+    document.addEventListener("click", (e) => {
+      const link = e.target.closest?.("a[href]");
+      if (
+        !link ||
+        e.defaultPrevented ||
+        e.button !== 0 ||
+        e.metaKey ||
+        e.ctrlKey ||
+        e.shiftKey ||
+        e.altKey ||
+        link.target ||
+        link.hasAttribute("download") ||
+        link.origin !== globalThis.location.origin
+      ) {
+        return;
+      }
+      e.preventDefault();
+      this.navigate(link.pathname + link.search);
+    });
+    globalThis.addEventListener("popstate", () => {
+      this.navigate(this.currentPath(), { replace: true });
+    });
+    this.navigate(this.currentPath(), { replace: true });
   }
+
+  currentPath() {
+    return globalThis.location.pathname + globalThis.location.search;
+  }
+
+  // navigate renders the page of path in the outlet and moves the history to
+  // it, then emits "route:changed". Paths the router does not know are
+  // loaded by the browser.
+  async navigate(path, { replace = false } = {}) {
+    // a new navigation aborts the one still rendering.
+    this.navigation?.abort();
+    const controller = new AbortController();
+    this.navigation = controller;
+    try {
+      const page = await this.wasmBridge.navigate(
+        path,
+        replace,
+        controller.signal,
+      );
+      const outlet = document.querySelector("[craft-outlet]");
+      outlet.innerHTML = page.html;
+      globalThis.scrollTo(0, 0);
+      this.route = page;
+      this.buildIndex();
+      this.emit("route:changed", {
+        pattern: page.pattern,
+        path: page.path,
+        params: page.params,
+      });
+    } catch (err) {
+      if (err?.name === "AbortError") return;
+      if (err?.code === "not_found" && !replace) {
+        globalThis.location.assign(path);
+        return;
+      }
+      console.error(`crafter: failed to navigate to ${path} (${err?.code}):`, err);
+    }
+  }
+  // shortcut runs the shortcut of the key pressed, from the "shortcuts" of
+  // the store. Keys typed into fields and chords are left to the page.
+  async shortcut(e) {
+    if (e.ctrlKey || e.metaKey || e.altKey || e.isComposing) return;
+    const target = e.target;
+    if (
+      target?.isContentEditable ||
+      ["INPUT", "TEXTAREA", "SELECT"].includes(target?.tagName)
+    ) {
+      return;
+    }
+    const key = e.key === " " ? "space" : e.key.toLowerCase();
+    const shortcut = (this.state.shortcuts ?? []).find(
+      (s) => s.key === key && (!s.scope || s.scope === this.route?.pattern),
+    );
+    if (!shortcut) return;
+    e.preventDefault();
+    // holding the key down runs it once.
+    if (e.repeat) return;
+
+    let result;
+    if (shortcut.handler) {
+      const args = [
+        ...parseCraftInput(shortcut.input),
+        ...(shortcut.args ?? []),
+      ];
+      try {
+        result = unwrap(
+          shortcut.handler,
+          await this.call(shortcut.handler, ...args),
+        );
+      } catch (err) {
+        if (err?.name !== "AbortError") {
+          console.error(`crafter: shortcut '${shortcut.action}' failed (${err?.code}):`, err);
+        }
+        return;
+      }
+      if (result === undefined) return;
+    }
+    this.emit("shortcut", { action: shortcut.action, result });
+  }
+
   buildIndex() {
+    this.applyBindings();
     document.querySelectorAll("[craft-name]").forEach((ele) => {
       if (!ele.getAttribute("craft-proceed")) this.handle(ele);
     });
   }
 
+  // applyBindings updates the elements bound to key, or to any key, with the
+  // values of the store.
+  applyBindings(key) {
+    document.querySelectorAll("[craft-bind]").forEach((ele) => {
+      const bind = parseCraftBind(ele.getAttribute("craft-bind"));
+      if (key !== undefined && bind.key !== key) return;
+      if (!(bind.key in this.state)) return;
+
+      let value = this.state[bind.key];
+      for (const field of bind.fields) value = value?.[field];
+      value = value ?? "";
+      const prop = bind.prop;
+      if (prop === undefined || prop === "innerText") {
+        ele.textContent = value;
+      } else if (prop === "innerHTML") {
+        ele.innerHTML = value;
+      } else if (prop.startsWith("[") && prop.endsWith("]")) {
+        ele.setAttribute(prop.slice(1, prop.length - 1), value);
+      }
+    });
+  }
+
   handle(ele) {
     if (this.isReady === false) {
       console.error("crafter: instance is not ready");
@@ -153,15 +317,29 @@ class Crafter {
 
       const isAsync = ele.getAttribute("craft-async") !== null;
 
+      const callback = (res) => {
+        const payload = unwrap(method, res);
+        if (payload !== undefined) callbackFn(payload);
+      };
+
       if (isAsync) {
-        handler(...parsed)
+        // a new trigger aborts the call still pending for this element.
+        ele.craftAbort?.abort();
+        const controller = new AbortController();
+        ele.craftAbort = controller;
+        handler(...parsed, controller.signal)
           .then(callbackFn)
+          .catch((err) => {
+            if (err?.name !== "AbortError") {
+              console.error(`crafter: '${method}' failed (${err?.code}):`, err);
+            }
+          })
           .then(() => {
             ele.setAttribute("craft-proceed", true);
-          })
-          .then(this.buildIndex());
+            this.buildIndex();
+          });
       } else {
-        callbackFn(handler(...parsed));
+        callback(handler(...parsed));
         ele.setAttribute("craft-proceed", true);
         this.buildIndex();
       }
@@ -183,6 +361,23 @@ class Crafter {
     }
   }
 
+  // on calls fn with the payload of every event name emitted by Go or by
+  // emit, it may subscribe before init. It returns the unsubscribe function.
+  on(name, fn) {
+    const listener = (e) => fn(e.detail);
+    globalThis.addEventListener(name, listener);
+    return () => globalThis.removeEventListener(name, listener);
+  }
+
+  // emit delivers an event to the listeners of the page and the Go
+  // subscribers.
+  emit(name, payload) {
+    globalThis.dispatchEvent(new CustomEvent(name, { detail: payload }));
+    if (this.isReady && this.wasmBridge?.emit) {
+      unwrap("emit", this.wasmBridge.emit(name, payload));
+    }
+  }
+
   call(name, ...args) {
     if (!this.isReady || !this.wasmBridge) {
       const errMsg = `Bridge not ready.`;
@@ -209,91 +404,100 @@ class Worker {
     this.crafter = crafter;
     this.currCard = null;
     this.isReady = false;
+    document.addEventListener("visibilitychange", () => {
+      if (document.visibilityState === "hidden") {
+        this.crafter.emit("page:hidden");
+      }
+    });
+    // the keyboard rates, undoes and suspends through the handlers, the
+    // page follows.
+    crafter.on("shortcut", ({ action }) => {
+      if (!this.isReady) return;
+      switch (action) {
+        case "reveal":
+          this.flipCard();
+          break;
+        case "rate-again":
+        case "rate-hard":
+        case "rate-good":
+        case "rate-easy":
+        case "undo":
+        case "suspend":
+          this.nextCard();
+          break;
+      }
+    });
   }
 
+  // start runs on every visit of the session page, its markup is new each
+  // time. The module keeps the session in progress.
   start() {
     const response = this.crafter.call("start");
-    if (response.error) {
-      console.error(response.error);
+    if (!response.success) {
+      console.error(response.code, response.error);
       return;
     }
-    if (response.payload) {
-      console.log(response);
-    }
+    // warm the audio cache for the whole session in the background.
+    this.crafter.call("prefetch");
+    this.bind();
     this.handleFetchCard();
     this.handleUpdateCard();
+    this.isReady = true;
+  }
 
-    try {
-      this.isReady = false;
-      this.isReady = true;
-
-      // Event Listeners
-      flashcard.addEventListener("click", this.flipCard.bind(this));
-
-      const ratingBtns = document.querySelectorAll(".rating-btn");
-      ratingBtns.forEach((btn) => {
-        btn.addEventListener("click", (e) => {
-          const rating = e.target.dataset.rating;
-          this.handleSubmitReview(rating);
-          this.nextCard();
-        });
+  // bind listens to the elements of the session page, once per rendering of
+  // it: later cards reuse the same elements.
+  bind() {
+    const flashcard = document.getElementById("flashcard");
+    if (flashcard.dataset.bound) return;
+    flashcard.dataset.bound = "true";
+
+    flashcard.addEventListener("click", () => this.flipCard());
+    document.querySelectorAll(".rating-btn").forEach((btn) => {
+      btn.addEventListener("click", (e) => {
+        this.handleSubmitReview(e.target.dataset.rating);
+        this.nextCard();
       });
+    });
 
-      this.handleUpdateCard();
-    } catch (err) {
-      console.error("Error fetching or parsing JSON:", err);
-    }
-  }
-  handleUpdateCard() {
     const wordEl = document.getElementById("word");
-    const ipaEl = document.getElementById("ipa");
-    const definitionEl = document.getElementById("definition");
-    const exampleEl = document.getElementById("example");
-    const flashcard = document.getElementById("flashcard");
-    const playIPASoundEl = document.getElementById("play-ipa");
-
-    flashcard.classList.remove("rotate-y-180");
-    ipaEl.textContent = this.currentCard.ipa;
-
-    // hack
-    ipaEl.removeAttribute("data");
-
-    wordEl.textContent = this.currentCard.word;
-    definitionEl.textContent = this.currentCard.definition;
-    exampleEl.textContent = `"${this.currentCard.example}"`;
-    wordEl.addEventListener("click", function (event) {
-      // Check if text is selected within the child element
+    wordEl.addEventListener("click", (event) => {
+      // selecting the word does not flip the card.
       const selection = globalThis.getSelection();
       const isTextSelected =
         selection.toString().length > 0 &&
         wordEl.contains(selection.anchorNode);
-
       if (isTextSelected) {
-        event.stopPropagation(); // Prevent the event from bubbling up to the parent
+        event.stopPropagation();
       }
     });
 
+    const playIPASoundEl = document.getElementById("play-ipa");
     playIPASoundEl.addEventListener("click", (ev) => {
-      // stop this ev propagating to parent object.
+      // playing the sound does not flip the card either.
       ev.stopPropagation();
       // blur the focus status immediately.
       playIPASoundEl.blur();
     });
   }
 
+  // handleUpdateCard shows the front of the card, its fields are bound to
+  // the "card" of the store.
+  handleUpdateCard() {
+    document.getElementById("flashcard").classList.remove("rotate-y-180");
+  }
+
   handleFetchCard() {
     const response = this.crafter.call("next");
-    if (response.error) {
-      console.error(response.error);
+    if (!response.success) {
+      console.error(response.code, response.error);
       return;
     }
-    if (response.stop) {
+    if (response.payload.stop) {
       console.log("stop");
       return;
     }
-    if (response.payload) {
-      this.currentCard = JSON.parse(response.payload);
-    }
+    this.currentCard = response.payload.card;
   }
 
   flipCard() {
@@ -304,10 +508,12 @@ class Worker {
   handleSubmitReview(rating) {
     const response = this.crafter.call(
       "submit",
-      JSON.stringify(this.currentCard.ID),
-      JSON.stringify(parseInt(rating)),
+      this.currentCard.ID,
+      parseInt(rating),
     );
-    console.log(response);
+    if (!response.success) {
+      console.error(response.code, response.error);
+    }
   }
 
   nextCard() {
@@ -0,0 +1,83 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"syscall/js"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+	"github.com/hnimtadd/spaced/src/review"
+)
+
+// Global JavaScript AudioContext instance
+var (
+	audioContext js.Value
+	soundSource  any
+)
+
+func init() {
+	// This function runs once when the WASM module is initialized.
+	// Create the AudioContext. It will likely be in 'suspended' state until user interaction.
+	audioContext = js.Global().Get("AudioContext").New()
+	fmt.Println("Go WASM: AudioContext created (state:", audioContext.Get("state").String(), ")")
+}
+
+type playRequest struct {
+	CardID int
+}
+
+// play pronounces the card, a new play of the same button aborts this one.
+func play(m *review.Manager) func(context.Context, playRequest) (string, error) {
+	return func(ctx context.Context, req playRequest) (string, error) {
+		sound, err := m.Sound(ctx, req.CardID)
+		if err != nil {
+			fmt.Println("failed to fetch sound", err)
+			return "", err
+		}
+		if err := playSound(ctx, sound); err != nil {
+			fmt.Println("failed to playsound", err)
+			return "", err
+		}
+		return "played", nil
+	}
+}
+
+// playSound decodes the audio/mpeg payload and plays it, it returns once the
+// playback started.
+func playSound(ctx context.Context, payload []byte) error {
+	fmt.Printf("Go WASM: Payload audio data length: %d bytes\n", len(payload))
+	// the context starts suspended until the first user interaction.
+	if audioContext.Get("state").String() == "suspended" {
+		if _, err := crafter.Await(ctx, audioContext.Call("resume")); err != nil {
+			return fmt.Errorf("failed to resume audio context: %w", err)
+		}
+	}
+
+	// Copy bytes from Go []byte to a JS ArrayBuffer
+	jsAudioBuffer := js.Global().Get("ArrayBuffer").New(len(payload))
+	js.CopyBytesToJS(js.Global().Get("Uint8Array").New(jsAudioBuffer), payload)
+
+	audioBuffer, err := crafter.Await(ctx, audioContext.Call("decodeAudioData", jsAudioBuffer))
+	if err != nil {
+		return fmt.Errorf("failed to decode audio data: %w", err)
+	}
+	fmt.Println("Go WASM: Audio decoded successfully.")
+
+	source := js.Global().Get("AudioBufferSourceNode").New(audioContext)
+	soundSource = source
+	source.Set("buffer", audioBuffer) // Set the decoded audio data
+	source.Call("connect", audioContext.Get("destination"))
+	var onEnded js.Func
+	onEnded = js.FuncOf(func(js.Value, []js.Value) any {
+		fmt.Println("Go WASM: Audio playback finished.")
+		source.Call("disconnect")
+		soundSource = nil
+		onEnded.Release()
+		return nil
+	})
+	source.Set("onended", onEnded)
+	source.Call("start", 0) // Play from the beginning
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build js && wasm
+
+// app is the module behind every page of the ui, the router of review.App
+// renders them in place.
+package main
+
+import (
+	"fmt"
+
+	"github.com/hnimtadd/spaced/src/crafter"
+	crafterhttp "github.com/hnimtadd/spaced/src/crafter/http"
+	"github.com/hnimtadd/spaced/src/review"
+)
+
+func main() {
+	wasm := crafter.NewWasm()
+	app := review.NewApp(crafter.Browser(), wasm.Events, crafterhttp.DefaultClient)
+	crafter.HandleAsync(wasm, "navigate", app.Router.Navigate)
+
+	m := app.Manager
+	crafter.HandleAsync(wasm, "init", m.Load)
+	crafter.Handle(wasm, "start", m.Start)
+	crafter.Handle(wasm, "next", m.Next)
+	crafter.Handle(wasm, "submit", m.Submit)
+	crafter.HandleAsync(wasm, "play", play(m))
+	crafter.Handle(wasm, "prefetch", m.Prefetch)
+	crafter.Handle(wasm, "undo", m.Undo)
+	crafter.Handle(wasm, "suspend", m.Suspend)
+
+	crafter.Handle(wasm, "stats", app.Stats.Render)
+	crafter.Handle(wasm, "replay", app.Stats.Replay)
+
+	crafter.Handle(wasm, "shortcuts", app.Keymap.Shortcuts)
+	crafter.Handle(wasm, "rebind", app.Keymap.Rebind)
+
+	fmt.Println(wasm.ListenAndServe())
+}